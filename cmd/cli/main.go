@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/cursus-io/tabellarius/pkg/bootstrap"
 	"github.com/cursus-io/tabellarius/pkg/config"
@@ -14,11 +19,46 @@ import (
 const (
 	ModeInspect = "inspect"
 	ModeInit    = "init"
+
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
 type RunOptions struct {
 	Mode  string
 	Apply bool
+	// Yes skips teardown's confirmation when data would be removed.
+	Yes bool
+	// Format is how inspect reports its checks: text or json.
+	Format      string
+	MaxDowntime time.Duration
+
+	// offset-set target
+	BinlogFile string
+	BinlogPos  uint
+	GTID       string
+	// Time is the offset-rewind target.
+	Time time.Time
+	// Checkpoint is the file offset-export writes and offset-import reads,
+	// "-" for stdout and stdin.
+	Checkpoint string
+
+	// tail filters: table names, optionally schema-qualified, and ops
+	Tables []string
+	Ops    []string
+	// Limit stops tail after that many transactions; zero streams until
+	// interrupted.
+	Limit int
+
+	// Binlog is the binlog file or directory decode and replay read,
+	// between BinlogFile/BinlogPos and StopFile/StopPos.
+	Binlog   string
+	StopFile string
+	StopPos  uint
+	// SchemaFile is the schema snapshot or DDL file decode and replay use
+	// for tables logged without column names, and where schema-export
+	// writes.
+	SchemaFile string
 }
 
 func Run(cfg *config.Config, db *sql.DB, opt RunOptions) error {
@@ -31,8 +71,7 @@ func Run(cfg *config.Config, db *sql.DB, opt RunOptions) error {
 
 	switch opt.Mode {
 	case ModeInspect:
-		_, err := bootstrap.Inspect(db, cfg)
-		return err
+		return inspect(cfg, db, opt)
 
 	case ModeInit:
 		if !opt.Apply {
@@ -40,34 +79,146 @@ func Run(cfg *config.Config, db *sql.DB, opt RunOptions) error {
 		}
 		return bootstrap.Init(db, cfg)
 
+	case ModeOffsetShow, ModeOffsetSet, ModeOffsetRewind, ModeOffsetReset, ModeOffsetExport, ModeOffsetImport:
+		return offset(cfg, db, opt)
+
+	case ModeTail:
+		return tail(cfg, db, opt)
+
+	case ModeDecode, ModeReplay:
+		return replay(cfg, opt)
+
+	case ModeSchemaExport:
+		return schemaExport(cfg, db, opt)
+
+	case ModeTeardown:
+		return teardown(cfg, db, opt)
+
 	default:
 		return fmt.Errorf("unknown mode: %s", opt.Mode)
 	}
 }
 
+// inspect runs the preflight checks and fails if any of them failed.
+func inspect(cfg *config.Config, db *sql.DB, opt RunOptions) error {
+	checks, err := bootstrap.Preflight(context.Background(), db, cfg, bootstrap.PreflightOptions{
+		MaxDowntime: opt.MaxDowntime,
+	})
+	if err != nil {
+		return err
+	}
+	failed := bootstrap.Failed(checks)
+
+	switch opt.Format {
+	case FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			OK     bool              `json:"ok"`
+			Checks []bootstrap.Check `json:"checks"`
+		}{failed == 0, checks}); err != nil {
+			return err
+		}
+	case FormatText, "":
+		bootstrap.PrintChecks(os.Stdout, checks)
+	default:
+		return fmt.Errorf("unknown format: %s", opt.Format)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d preflight checks failed", failed, len(checks))
+	}
+	return nil
+}
+
 func main() {
 	var (
-		mode  = flag.String("mode", ModeInspect, ModeInspect+"|"+ModeInit)
+		mode = flag.String("mode", ModeInspect, strings.Join([]string{
+			ModeInspect, ModeInit, ModeTail, ModeDecode, ModeReplay, ModeSchemaExport, ModeTeardown,
+			ModeOffsetShow, ModeOffsetSet, ModeOffsetRewind, ModeOffsetReset, ModeOffsetExport, ModeOffsetImport,
+		}, "|"))
 		conf  = flag.String("config", "cdc-config.yaml", "config path")
+		name  = flag.String("pipeline", "", "pipeline to work on when the config has several")
 		apply = flag.Bool("apply", false, "apply changes")
+		yes   = flag.Bool("yes", false, "teardown without confirming when data would be removed")
+
+		format   = flag.String("format", FormatText, "inspect and tail output: "+FormatText+"|"+FormatJSON)
+		downtime = flag.Duration("max-downtime", 24*time.Hour, "longest expected pipeline downtime; binlogs must be kept at least this long")
+
+		binlogFile = flag.String("binlog-file", "", "offset-set, tail, decode and replay start binlog file")
+		binlogPos  = flag.Uint("binlog-pos", 4, "offset-set, tail, decode and replay start binlog position")
+		gtid       = flag.String("gtid", "", "offset-set and tail gtid set")
+		checkpoint = flag.String("checkpoint", "-", "offset-export/offset-import file (- for stdout/stdin)")
+		at         time.Time
+
+		tables = flag.String("tables", "", "tail and decode only these comma-separated tables")
+		ops    = flag.String("ops", "", "tail and decode only these comma-separated ops: insert, update, delete, truncate")
+		limit  = flag.Int("limit", 0, "stop tail and decode after this many transactions (0 for no limit)")
+
+		binlog     = flag.String("binlog", "", "binlog file or directory to decode or replay")
+		stopFile   = flag.String("stop-file", "", "decode and replay stop binlog file")
+		stopPos    = flag.Uint("stop-pos", 0, "decode and replay stop position in --stop-file (0 for its end)")
+		schemaFile = flag.String("schema-file", "", "schema snapshot or DDL file for decode and replay; schema-export output")
 	)
+	flag.Func("time", "offset-rewind and tail start time (RFC 3339)", func(s string) (err error) {
+		at, err = time.Parse(time.RFC3339, s)
+		return err
+	})
 	flag.Parse()
 
-	cfg, err := config.Load(*conf)
+	srv, err := config.LoadServer(*conf)
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
+	if err := srv.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := srv.Pipeline(*name)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	driver := cfg.Database.Type.DriverName()
-	db, err := sql.Open(driver, cfg.DSN())
+	dsn, err := cfg.DSN()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := sql.Open(cfg.Database.Type.DriverName(), dsn)
 	if err != nil {
 		log.Fatalf("failed to open db: %v", err)
 	}
 	defer db.Close()
 
-	log.Printf("mode=%s apply=%v", *mode, *apply)
+	log.Printf("mode=%s apply=%v pipeline=%s dsn=%s", *mode, *apply, cfg.Name, cfg.MaskedDSN())
 
-	if err := Run(cfg, db, RunOptions{Mode: *mode, Apply: *apply}); err != nil {
+	if err := Run(cfg, db, RunOptions{
+		Mode:        *mode,
+		Apply:       *apply,
+		Yes:         *yes,
+		Format:      *format,
+		MaxDowntime: *downtime,
+		BinlogFile:  *binlogFile,
+		BinlogPos:   *binlogPos,
+		GTID:        *gtid,
+		Time:        at,
+		Checkpoint:  *checkpoint,
+		Tables:      splitList(*tables),
+		Ops:         splitList(*ops),
+		Limit:       *limit,
+		Binlog:      *binlog,
+		StopFile:    *stopFile,
+		StopPos:     *stopPos,
+		SchemaFile:  *schemaFile,
+	}); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
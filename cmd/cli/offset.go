@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/source"
+	"github.com/cursus-io/tabellarius/pkg/util"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+)
+
+const (
+	ModeOffsetShow   = "offset-show"
+	ModeOffsetSet    = "offset-set"
+	ModeOffsetRewind = "offset-rewind"
+	ModeOffsetReset  = "offset-reset"
+	ModeOffsetExport = "offset-export"
+	ModeOffsetImport = "offset-import"
+)
+
+// Checkpoint is a pipeline's binlog checkpoint as offset-export writes it and
+// offset-import reads it.
+type Checkpoint struct {
+	Pipeline string             `json:"pipeline"`
+	Database model.DatabaseType `json:"database"`
+	Schema   string             `json:"schema"`
+	Offset   model.MySQLOffset  `json:"offset"`
+}
+
+// offset runs the offset modes. Those that move the checkpoint only print the
+// change unless opt.Apply is set, and all of them except show and export
+// refuse while a pipeline holds the lock.
+func offset(cfg *config.Config, db *sql.DB, opt RunOptions) error {
+	if !cfg.Database.Type.IsBinlogBased() {
+		return fmt.Errorf("offset modes support mysql and mariadb, not %s", cfg.Database.Type)
+	}
+	path := source.BinlogOffsetPath(cfg.CDCServer.OffsetFile)
+	cur, ok := util.LoadJSON[model.MySQLOffset](path)
+
+	switch opt.Mode {
+	case ModeOffsetShow:
+		if !ok {
+			fmt.Printf("%s: no checkpoint, the pipeline starts from the server's current position\n", path)
+			return nil
+		}
+		fmt.Printf("%s: %s\n", path, describe(cur))
+		return nil
+
+	case ModeOffsetExport:
+		if !ok {
+			return fmt.Errorf("no checkpoint in %s", path)
+		}
+		return writeCheckpoint(opt.Checkpoint, Checkpoint{
+			Pipeline: cfg.Name,
+			Database: cfg.Database.Type,
+			Schema:   cfg.Database.Schema,
+			Offset:   cur,
+		})
+	}
+
+	lock, err := util.LockFile(source.LockPath(cfg.CDCServer.OffsetFile))
+	if errors.Is(err, util.ErrLocked) {
+		return fmt.Errorf("pipeline is running (%w); stop cdc-server first", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	next, err := targetOffset(context.Background(), cfg, db, opt)
+	if err != nil {
+		return err
+	}
+
+	from := "none"
+	if ok {
+		from = describe(cur)
+	}
+	fmt.Printf("%s: %s -> %s\n", path, from, describe(next))
+	if !opt.Apply {
+		fmt.Println("dry run, re-run with --apply to write it")
+		return nil
+	}
+	return util.SaveJSON(path, next)
+}
+
+// targetOffset returns the checkpoint opt.Mode moves to.
+func targetOffset(ctx context.Context, cfg *config.Config, db *sql.DB, opt RunOptions) (model.MySQLOffset, error) {
+	switch opt.Mode {
+	case ModeOffsetSet:
+		return setOffset(cfg.Database.Type, opt)
+
+	case ModeOffsetRewind, ModeOffsetReset:
+		dsn, err := cfg.DSN()
+		if err != nil {
+			return model.MySQLOffset{}, err
+		}
+		ins, err := inspector.NewBinlogInspector(db, cfg.Database.Type, cfg.Database.Schema, dsn,
+			source.BinlogOffsetPath(cfg.CDCServer.OffsetFile), util.GenerateID(), cfg.Tables, inspector.Options{})
+		if err != nil {
+			return model.MySQLOffset{}, err
+		}
+		if opt.Mode == ModeOffsetReset {
+			return ins.CurrentOffset(ctx)
+		}
+		if opt.Time.IsZero() {
+			return model.MySQLOffset{}, errors.New("--time is required for offset-rewind")
+		}
+		return ins.FindOffset(ctx, opt.Time)
+
+	case ModeOffsetImport:
+		c, err := readCheckpoint(opt.Checkpoint)
+		if err != nil {
+			return model.MySQLOffset{}, err
+		}
+		if c.Database != cfg.Database.Type {
+			return model.MySQLOffset{}, fmt.Errorf("checkpoint is for %s, not %s", c.Database, cfg.Database.Type)
+		}
+		if c.Pipeline != cfg.Name || c.Schema != cfg.Database.Schema {
+			log.Printf("[WARN] checkpoint was exported from pipeline %q schema %q", c.Pipeline, c.Schema)
+		}
+		if c.Offset.File == "" && c.Offset.GTIDSet == "" {
+			return model.MySQLOffset{}, errors.New("checkpoint has no offset")
+		}
+		return c.Offset, nil
+
+	default:
+		return model.MySQLOffset{}, fmt.Errorf("unknown mode: %s", opt.Mode)
+	}
+}
+
+func setOffset(dbType model.DatabaseType, opt RunOptions) (model.MySQLOffset, error) {
+	pos, err := binlogPos("binlog-pos", opt.BinlogPos)
+	if err != nil {
+		return model.MySQLOffset{}, err
+	}
+	off := model.MySQLOffset{File: opt.BinlogFile, Pos: pos, GTIDSet: opt.GTID}
+
+	switch {
+	case off.File == "" && off.GTIDSet == "":
+		return off, errors.New("offset-set needs --binlog-file and --binlog-pos, or --gtid")
+	case off.File != "" && off.Pos < 4:
+		// a binlog file starts with a 4-byte magic number
+		return off, fmt.Errorf("--binlog-pos must be at least 4, got %d", off.Pos)
+	case off.File == "" && dbType == model.MariaDB:
+		// binlog availability on MariaDB is checked by file
+		return off, errors.New("--binlog-file is required alongside --gtid on mariadb")
+	}
+	if off.GTIDSet != "" {
+		if _, err := mysql.ParseGTIDSet(dbType.BinlogFlavor(), off.GTIDSet); err != nil {
+			return off, fmt.Errorf("invalid --gtid: %w", err)
+		}
+	}
+	return off, nil
+}
+
+// binlogPos narrows the position given to --name to the 32 bits binlog
+// positions have.
+func binlogPos(name string, v uint) (uint32, error) {
+	if v > math.MaxUint32 {
+		return 0, fmt.Errorf("--%s must be at most %d, got %d", name, uint32(math.MaxUint32), v)
+	}
+	return uint32(v), nil
+}
+
+func describe(off model.MySQLOffset) string {
+	s := off.String()
+	if off.File == "" {
+		s = "(no file)"
+	}
+	if off.GTIDSet != "" {
+		s += " gtid " + off.GTIDSet
+	}
+	return s
+}
+
+func writeCheckpoint(path string, c Checkpoint) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func readCheckpoint(path string) (Checkpoint, error) {
+	var c Checkpoint
+
+	r := io.Reader(os.Stdin)
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return c, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("read checkpoint: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/source"
+)
+
+const (
+	ModeDecode       = "decode"
+	ModeReplay       = "replay"
+	ModeSchemaExport = "schema-export"
+)
+
+// replay decodes local binlog files without a database connection, printing
+// their transactions like tail (decode) or publishing them to the configured
+// sink (replay).
+func replay(cfg *config.Config, opt RunOptions) error {
+	if !cfg.Database.Type.IsBinlogBased() {
+		return fmt.Errorf("%s supports mysql and mariadb, not %s", opt.Mode, cfg.Database.Type)
+	}
+	if opt.Binlog == "" {
+		return fmt.Errorf("--binlog is required for %s mode", opt.Mode)
+	}
+	if opt.Mode == ModeReplay && !opt.Apply {
+		return fmt.Errorf("--apply flag required for replay mode; preview with --mode=%s", ModeDecode)
+	}
+	startPos, err := binlogPos("binlog-pos", opt.BinlogPos)
+	if err != nil {
+		return err
+	}
+	stopPos, err := binlogPos("stop-pos", opt.StopPos)
+	if err != nil {
+		return err
+	}
+
+	files, err := inspector.BinlogFiles(opt.Binlog)
+	if err != nil {
+		return err
+	}
+	var schema map[string]inspector.TableSchema
+	if opt.SchemaFile != "" {
+		if schema, err = inspector.LoadSchema(opt.SchemaFile, cfg.Database.Schema); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var pub source.Publisher
+	if opt.Mode == ModeDecode {
+		pub, err = newTailPublisher(cfg, opt, os.Stdout, cancel)
+	} else {
+		pub, err = source.NewPublisher(cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	spillDir, err := os.MkdirTemp("", "tabellarius-replay-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(spillDir)
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return err
+	}
+	reg := metrics.NewRegistry()
+	ins, err := inspector.NewBinlogInspector(nil, cfg.Database.Type, cfg.Database.Schema, dsn, "", 0, cfg.Tables, inspector.Options{
+		Statements: cfg.Database.Statements,
+		Metrics:    reg,
+		Schema:     schema,
+	})
+	if err != nil {
+		return err
+	}
+	reader := ins.ReadFiles(files, inspector.FileOptions{
+		Start: model.MySQLOffset{File: opt.BinlogFile, Pos: startPos},
+		Stop:  model.MySQLOffset{File: opt.StopFile, Pos: stopPos},
+	})
+	log.Printf("[%s] reading %d binlog files from %s", opt.Mode, len(files), opt.Binlog)
+
+	src := source.NewWithInspector(reader, pub, source.Options{
+		Buffer: source.BufferOptions{
+			MaxMemoryBytes: cfg.CDCServer.Buffer.MaxMemoryBytes,
+			SpillDir:       spillDir,
+		},
+		Fragment: source.FragmentOptions{
+			MaxRows:  cfg.CDCServer.Fragment.MaxRows,
+			MaxBytes: cfg.CDCServer.Fragment.MaxBytes,
+		},
+		Metrics: reg,
+	})
+	src.Start(ctx)
+
+	// the source stops once the files are read, flushing the sink
+	<-src.Done()
+	select {
+	case err := <-src.Err():
+		return err
+	default:
+		return nil
+	}
+}
+
+// schemaExport writes the captured tables' columns and engines as a schema
+// snapshot for decode and replay.
+func schemaExport(cfg *config.Config, db *sql.DB, opt RunOptions) error {
+	if !cfg.Database.Type.IsBinlogBased() {
+		return fmt.Errorf("schema-export supports mysql and mariadb, not %s", cfg.Database.Type)
+	}
+	if len(cfg.Tables) == 0 {
+		return errors.New("no tables configured")
+	}
+
+	tables := make([]string, 0, len(cfg.Tables))
+	for _, t := range cfg.Tables {
+		tables = append(tables, t.Name)
+	}
+	schema, err := inspector.DumpSchema(context.Background(), db, cfg.Database.Schema, tables)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if opt.SchemaFile == "" || opt.SchemaFile == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(opt.SchemaFile, b, 0644)
+}
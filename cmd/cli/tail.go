@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/format"
+	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/source"
+)
+
+const ModeTail = "tail"
+
+// tail streams the pipeline's changes to stdout as its sink would receive
+// them, without a sink and without persisting offsets, until interrupted or
+// opt.Limit transactions were printed.
+func tail(cfg *config.Config, db *sql.DB, opt RunOptions) error {
+	if !cfg.Database.Type.IsBinlogBased() {
+		return fmt.Errorf("tail supports mysql and mariadb, not %s", cfg.Database.Type)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	pub, err := newTailPublisher(cfg, opt, os.Stdout, cancel)
+	if err != nil {
+		return err
+	}
+	start, err := tailStart(ctx, cfg, db, opt)
+	if err != nil {
+		return err
+	}
+	log.Printf("[tail] streaming from %s", describe(start))
+
+	// a spill dir of its own, as buffers clear theirs on start
+	spillDir, err := os.MkdirTemp("", "tabellarius-tail-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(spillDir)
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return err
+	}
+	reg := metrics.NewRegistry()
+	src, err := source.NewMySQLSource(db, cfg.Database.Type, cfg.Database.Schema, dsn, "", pub, cfg.Tables, inspector.Options{
+		Statements: cfg.Database.Statements,
+		Metrics:    reg,
+		Start:      start,
+	}, source.Options{
+		Buffer: source.BufferOptions{
+			MaxMemoryBytes: cfg.CDCServer.Buffer.MaxMemoryBytes,
+			SpillDir:       spillDir,
+		},
+		Metrics: reg,
+	})
+	if err != nil {
+		return err
+	}
+
+	src.Start(ctx)
+	select {
+	case <-src.Done():
+		return nil
+	case err := <-src.Err():
+		cancel()
+		<-src.Done()
+		return err
+	}
+}
+
+// tailStart is where tail starts: the position or GTID set given, the first
+// transaction at --time, or the server's current position.
+func tailStart(ctx context.Context, cfg *config.Config, db *sql.DB, opt RunOptions) (model.MySQLOffset, error) {
+	switch {
+	case opt.BinlogFile != "" || opt.GTID != "":
+		opt.Mode = ModeOffsetSet
+	case !opt.Time.IsZero():
+		opt.Mode = ModeOffsetRewind
+	default:
+		opt.Mode = ModeOffsetReset
+	}
+	return targetOffset(ctx, cfg, db, opt)
+}
+
+// tailPublisher prints the events it is given, keeping only the row changes
+// of the tables and operations asked for.
+type tailPublisher struct {
+	w io.Writer
+	// enc prints JSON; nil prints text.
+	enc    format.Encoder
+	tables map[string]bool
+	ops    map[model.OpType]bool
+
+	limit   int
+	printed int
+	// open is the transaction whose earlier fragments were printed.
+	open string
+	stop func()
+}
+
+func newTailPublisher(cfg *config.Config, opt RunOptions, w io.Writer, stop func()) (*tailPublisher, error) {
+	p := &tailPublisher{w: w, limit: opt.Limit, stop: stop}
+
+	if len(opt.Tables) > 0 {
+		p.tables = map[string]bool{}
+		for _, t := range opt.Tables {
+			if !strings.Contains(t, ".") {
+				t = cfg.Database.Schema + "." + t
+			}
+			p.tables[t] = true
+		}
+	}
+	if len(opt.Ops) > 0 {
+		p.ops = map[model.OpType]bool{}
+		for _, op := range opt.Ops {
+			switch o := model.OpType(strings.ToUpper(op)); o {
+			case model.OpInsert, model.OpUpdate, model.OpDelete, model.OpTruncate:
+				p.ops[o] = true
+			default:
+				return nil, fmt.Errorf("unknown op %q, expected insert, update, delete or truncate", op)
+			}
+		}
+	}
+
+	switch opt.Format {
+	case FormatText, "":
+	case FormatJSON:
+		name := cfg.Sink.Format
+		if name == format.Avro || name == format.Protobuf {
+			log.Printf("[tail] sink format %s is binary, printing json", name)
+			name = format.JSON
+		}
+		enc, err := format.New(name, format.Options{
+			ServerName:      cfg.Sink.ServerName,
+			CloudEventsMode: cfg.Sink.CloudEventsMode,
+			CanalTxMarkers:  cfg.Sink.CanalTxMarkers,
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.enc = enc
+	default:
+		return nil, fmt.Errorf("unknown format: %s", opt.Format)
+	}
+	return p, nil
+}
+
+func (p *tailPublisher) Publish(evt model.Event) error {
+	if p.limit > 0 && p.printed >= p.limit {
+		return nil
+	}
+
+	if tx, ok := evt.(*model.TransactionEvent); ok {
+		// a fragmented transaction counts once, when its last fragment
+		// arrives, provided any of its fragments was printed
+		frag, fragmented := tx.Fragment()
+		last := !fragmented || frag.Last
+		changes := p.filter(tx.Changes())
+		if len(changes) == 0 {
+			if last && p.open == tx.TxID() {
+				p.done()
+			}
+			return nil
+		}
+
+		if fragmented {
+			evt = model.NewTransactionFragment(tx.Source(), tx.Offset(), tx.Timestamp(), tx.TxID(), changes, tx.Meta(), frag)
+		} else {
+			evt = model.NewTransactionEvent(tx.Source(), tx.Offset(), tx.Timestamp(), tx.TxID(), changes, tx.Meta())
+		}
+		if err := p.print(evt); err != nil {
+			return err
+		}
+		if last {
+			p.done()
+		} else {
+			p.open = tx.TxID()
+		}
+		return nil
+	}
+	return p.print(evt)
+}
+
+// done counts a printed transaction, stopping once the limit is reached.
+func (p *tailPublisher) done() {
+	p.open = ""
+	p.printed++
+	if p.limit > 0 && p.printed >= p.limit {
+		p.stop()
+	}
+}
+
+func (p *tailPublisher) filter(changes []model.RowChange) []model.RowChange {
+	if p.tables == nil && p.ops == nil {
+		return changes
+	}
+	var out []model.RowChange
+	for _, c := range changes {
+		if p.tables != nil && !p.tables[c.Schema+"."+c.Table] {
+			continue
+		}
+		if p.ops != nil && !p.ops[c.Op] {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (p *tailPublisher) print(evt model.Event) error {
+	if p.enc != nil {
+		msgs, err := p.enc.Encode(evt)
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Value != nil {
+				fmt.Fprintf(p.w, "%s\n", m.Value)
+			}
+		}
+		return nil
+	}
+
+	switch e := evt.(type) {
+	case *model.TransactionEvent:
+		tx := e.TxID()
+		if f, ok := e.Fragment(); ok {
+			tx += fmt.Sprintf(" fragment %d", f.Index)
+		}
+		fmt.Fprintf(p.w, "--- tx %s at %s, %s\n", tx, e.Offset(), e.Timestamp().UTC().Format(time.RFC3339))
+		for _, c := range e.Changes() {
+			printChange(p.w, c)
+		}
+	case *model.BinlogDDLEvent:
+		fmt.Fprintf(p.w, "--- ddl at %s\n%s: %s\n", e.Offset(), e.Schema(), e.Query())
+	case *model.WarningEvent:
+		fmt.Fprintf(p.w, "--- warning at %s\n%s: %s\n", e.Offset(), e.Code(), e.Message())
+	}
+	return nil
+}
+
+func printChange(w io.Writer, c model.RowChange) {
+	table := c.Schema + "." + c.Table
+	if c.Statement != "" {
+		fmt.Fprintf(w, "-- %s\n", c.Statement)
+	}
+	if len(c.Rows) == 0 {
+		fmt.Fprintf(w, "%s %s\n", c.Op, table)
+		return
+	}
+	for _, r := range c.Rows {
+		switch c.Op {
+		case model.OpInsert:
+			fmt.Fprintf(w, "%s %s %s\n", c.Op, table, compact(r.After))
+		case model.OpDelete:
+			fmt.Fprintf(w, "%s %s %s\n", c.Op, table, compact(r.Before))
+		default:
+			fmt.Fprintf(w, "%s %s %s -> %s\n", c.Op, table, compact(r.Before), compact(r.After))
+		}
+	}
+}
+
+func compact(row map[string]any) string {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Sprint(row)
+	}
+	return string(b)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cursus-io/tabellarius/pkg/bootstrap"
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/source"
+	"github.com/cursus-io/tabellarius/pkg/util"
+)
+
+const ModeTeardown = "teardown"
+
+// teardown removes what init and the pipeline created: the cdc_log table, the
+// checkpoint files and, last, the lock file. It prints the plan, and only carries it out
+// with --apply; when data would be lost the schema name must be typed to
+// confirm, unless --yes is given.
+func teardown(cfg *config.Config, db *sql.DB, opt RunOptions) error {
+	ctx := context.Background()
+	offsetFile := cfg.CDCServer.OffsetFile
+	lockPath := source.LockPath(offsetFile)
+
+	// taking the lock writes our pid into its file, so it stays out of the
+	// plan and its data check; it is removed last, while still held
+	lock, err := util.LockFile(lockPath)
+	if errors.Is(err, util.ErrLocked) {
+		return fmt.Errorf("pipeline is running (%w); stop cdc-server first", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	plan, err := bootstrap.PlanTeardown(ctx, db, cfg, []string{offsetFile, source.BinlogOffsetPath(offsetFile)})
+	if err != nil {
+		return err
+	}
+	bootstrap.PrintPlan(os.Stdout, plan)
+	fmt.Printf("[DROP] %s %s (lock, removed last)\n", bootstrap.RemoveFile, lockPath)
+
+	if !opt.Apply {
+		fmt.Println("dry run, re-run with --apply to remove it")
+		return nil
+	}
+	if !opt.Yes && slices.ContainsFunc(plan, bootstrap.Removal.HasData) {
+		ok, err := confirm(os.Stdin, os.Stdout, cfg.Database.Schema)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("teardown not confirmed")
+		}
+	}
+	if err := bootstrap.Teardown(ctx, db, plan); err != nil {
+		return err
+	}
+
+	// a cdc-server starting from here on takes a fresh lock and finds
+	// nothing to resume from
+	if err := os.Remove(lockPath); err != nil {
+		return err
+	}
+	fmt.Printf("[cli] removed %s %s\n", bootstrap.RemoveFile, lockPath)
+	return nil
+}
+
+// confirm asks for want to be typed back before data is removed.
+func confirm(r io.Reader, w io.Writer, want string) (bool, error) {
+	fmt.Fprintf(w, "this removes captured data and checkpoints; type %q to confirm: ", want)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return strings.TrimSpace(line) == want, nil
+}
@@ -2,45 +2,45 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/cursus-io/tabellarius/pkg/bootstrap"
 	"github.com/cursus-io/tabellarius/pkg/config"
-	"github.com/cursus-io/tabellarius/pkg/source"
+	"github.com/cursus-io/tabellarius/pkg/pipeline"
 	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
 	confPath := flag.String("config", "cdc-config.yaml", "config file path")
+	adminAddr := flag.String("admin", "", "admin API listen address (overrides admin.addr)")
+	watch := flag.Duration("watch", 5*time.Second, "config file poll interval for live reloads (0 disables; SIGHUP always reloads)")
 	flag.Parse()
 
-	cfg, err := config.Load(*confPath)
+	srv, err := config.LoadServer(*confPath)
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
-
-	db, err := connectWithRetry(cfg.Database.Type.DriverName(), cfg.DSN(), 3)
-	if err != nil {
+	if err := srv.Validate(); err != nil {
 		log.Fatalf("[FATAL] %v", err)
 	}
-	defer db.Close()
-
-	log.Println("[OK] db connected")
-
-	ok, err := bootstrap.Inspect(db, cfg)
-	if err != nil {
-		log.Fatalf("[FATAL] inspect failed: %v", err)
+	fileAdmin := srv.Admin
+	if *adminAddr != "" {
+		srv.Admin.Addr = *adminAddr
 	}
 
-	if !ok {
-		log.Fatalf("[FATAL] cdc_log table not found. bootstrap required")
+	pipelines := make([]*pipeline.Pipeline, 0, len(srv.Pipelines))
+	for i := range srv.Pipelines {
+		p := pipeline.New(&srv.Pipelines[i])
+		log.Printf("[INFO] pipeline %s database %s", p.Name(), p.Status().Database)
+		pipelines = append(pipelines, p)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -49,38 +49,89 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	src := source.NewFromConfig(db, cfg)
-	src.Start(ctx)
-
-	sig := <-sigChan
-	log.Printf("[INFO] received signal (%s). starting graceful shutdown...", sig)
-
-	cancel()
+	var admin *http.Server
+	if srv.Admin.Addr != "" {
+		admin = &http.Server{Addr: srv.Admin.Addr, Handler: pipeline.Handler(pipelines)}
+		go func() {
+			if err := admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[WARN] admin api stopped: %v", err)
+			}
+		}()
+		log.Printf("[INFO] admin api listening on %s", srv.Admin.Addr)
+	}
 
-	time.Sleep(2 * time.Second)
-	log.Println("[OK] tabellarius stopped safely.")
-}
+	// each pipeline restarts on its own, so one failing does not stop the
+	// rest; a fatal error stops only that pipeline
+	var wg sync.WaitGroup
+	fatal := make(chan error, len(pipelines))
+	for _, p := range pipelines {
+		wg.Go(func() {
+			if err := p.Run(ctx); err != nil {
+				fatal <- fmt.Errorf("pipeline %s: %w", p.Name(), err)
+			}
+		})
+	}
 
-func connectWithRetry(driver, dsn string, maxRetry int) (*sql.DB, error) {
-	var lastErr error
+	reloads := make(chan struct{}, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	if *watch > 0 {
+		go config.Watch(ctx, *confPath, *watch, func() {
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		})
+	}
 
-	for i := 1; i <= maxRetry; i++ {
-		db, err := sql.Open(driver, dsn)
-		if err == nil {
-			if pingErr := db.Ping(); pingErr == nil {
-				log.Printf("[OK] db connected (attempt=%d)", i)
-				return db, nil
-			} else {
-				lastErr = pingErr
-				db.Close()
+	exitCode, failed := 0, 0
+	for running := true; running; {
+		select {
+		case sig := <-sigChan:
+			log.Printf("[INFO] received signal (%s). starting graceful shutdown...", sig)
+			running = false
+		case err := <-fatal:
+			log.Printf("[FATAL] %v", err)
+			// nothing is left running once every pipeline has failed
+			if failed++; failed == len(pipelines) {
+				exitCode = 1
+				running = false
 			}
-		} else {
-			lastErr = err
+		case <-hup:
+			log.Printf("[INFO] received SIGHUP, reloading %s", *confPath)
+			reload(*confPath, fileAdmin, pipelines)
+		case <-reloads:
+			log.Printf("[INFO] %s changed, reloading", *confPath)
+			reload(*confPath, fileAdmin, pipelines)
 		}
+	}
+	cancel()
 
-		log.Printf("[WARN] db connection failed (attempt=%d/%d): %v", i, maxRetry, lastErr)
-		time.Sleep(time.Duration(i) * time.Second)
+	if admin != nil {
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = admin.Shutdown(shutdownCtx)
+		done()
+	}
+	wg.Wait()
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
+	log.Println("[OK] tabellarius stopped safely.")
+}
 
-	return nil, fmt.Errorf("db connection failed after %d attempts: %w", maxRetry, lastErr)
+// reload applies the safe changes in the config at path to the running
+// pipelines; a config that does not load or validate is ignored.
+func reload(path string, admin config.Admin, pipelines []*pipeline.Pipeline) {
+	next, err := config.LoadServer(path)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		log.Printf("[reload] keeping the running config: %v", err)
+		return
+	}
+	if next.Admin != admin {
+		log.Printf("[reload] admin.addr: restart required")
+	}
+	pipeline.Reload(pipelines, next)
 }
@@ -0,0 +1,426 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "OK"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Check is the outcome of one preflight check, with a hint on how to fix
+// anything that is not OK.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+type PreflightOptions struct {
+	// MaxDowntime is the longest the pipeline is expected to be stopped;
+	// binlogs must be kept at least this long to resume without loss.
+	MaxDowntime time.Duration
+}
+
+// preflightVars are the server variables the checks look at.
+var preflightVars = []string{
+	"log_bin", "binlog_format", "binlog_row_image", "binlog_row_metadata",
+	"gtid_mode", "binlog_expire_logs_seconds", "expire_logs_days", "server_id",
+}
+
+// facts is what the checks need to know about the server, gathered up front
+// so the checks themselves need no connection.
+type facts struct {
+	dbType model.DatabaseType
+	vars   map[string]string
+
+	grants    []string
+	grantsErr error
+
+	replicaIDs  []uint32
+	replicasErr error
+
+	// columns maps each table of the schema to its columns, and primary
+	// to those in its primary key.
+	columns map[string][]string
+	primary map[string][]string
+}
+
+// Preflight checks that the server and schema are ready for replication.
+func Preflight(ctx context.Context, db *sql.DB, cfg *config.Config, opts PreflightOptions) ([]Check, error) {
+	if !cfg.Database.Type.IsBinlogBased() {
+		return nil, fmt.Errorf("preflight supports mysql and mariadb, not %s", cfg.Database.Type)
+	}
+
+	f, err := gather(ctx, db, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return evaluate(f, cfg, opts), nil
+}
+
+func gather(ctx context.Context, db *sql.DB, cfg *config.Config) (*facts, error) {
+	f := &facts{
+		dbType:  cfg.Database.Type,
+		vars:    map[string]string{},
+		columns: map[string][]string{},
+		primary: map[string][]string{},
+	}
+
+	query := "SHOW GLOBAL VARIABLES WHERE Variable_name IN (?" + strings.Repeat(", ?", len(preflightVars)-1) + ")"
+	args := make([]any, len(preflightVars))
+	for i, v := range preflightVars {
+		args[i] = v
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read server variables: %w", err)
+	}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		f.vars[strings.ToLower(name)] = value
+	}
+	rows.Close()
+
+	f.grants, f.grantsErr = queryStrings(ctx, db, "SHOW GRANTS FOR CURRENT_USER()")
+	f.replicaIDs, f.replicasErr = replicaIDs(ctx, db)
+
+	rows, err = db.QueryContext(ctx, `
+SELECT TABLE_NAME, COLUMN_NAME, COLUMN_KEY
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = ?
+ORDER BY TABLE_NAME, ORDINAL_POSITION`, cfg.Database.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("read table columns: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, key string
+		if err := rows.Scan(&table, &column, &key); err != nil {
+			return nil, err
+		}
+		f.columns[table] = append(f.columns[table], column)
+		if key == "PRI" {
+			f.primary[table] = append(f.primary[table], column)
+		}
+	}
+	return f, rows.Err()
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// replicaIDs lists the server ids of the replicas registered with the
+// server; SHOW REPLICAS needs MySQL 8.0.22, older servers and MariaDB only
+// know SHOW SLAVE HOSTS.
+func replicaIDs(ctx context.Context, db *sql.DB) ([]uint32, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICAS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE HOSTS")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	idCol := slices.IndexFunc(cols, func(c string) bool { return strings.EqualFold(c, "Server_id") })
+	if idCol < 0 {
+		return nil, fmt.Errorf("no Server_id column in %v", cols)
+	}
+
+	var ids []uint32
+	for rows.Next() {
+		vals := make([]sql.RawBytes, len(cols))
+		dest := make([]any, len(cols))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if id, err := strconv.ParseUint(string(vals[idCol]), 10, 32); err == nil {
+			ids = append(ids, uint32(id))
+		}
+	}
+	return ids, rows.Err()
+}
+
+func evaluate(f *facts, cfg *config.Config, opts PreflightOptions) []Check {
+	checks := []Check{
+		checkLogBin(f),
+		checkBinlogFormat(f),
+		checkRowImage(f),
+		checkRowMetadata(f),
+		checkGTID(f),
+		checkRetention(f, opts.MaxDowntime),
+		checkPrivileges(f, cfg.Database.Schema, cfg.Tables),
+		checkServerID(f),
+		checkTable(f, cfg.Database.Schema, cfg.CdcLog.Table, ""),
+	}
+	for _, t := range cfg.Tables {
+		checks = append(checks, checkTable(f, cfg.Database.Schema, t.Name, t.PK))
+	}
+	return checks
+}
+
+func checkLogBin(f *facts) Check {
+	c := Check{Name: "log_bin"}
+	switch v := f.vars["log_bin"]; v {
+	case "ON", "1":
+		c.Status, c.Detail = StatusOK, "binary logging is on"
+	default:
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("binary logging is off (log_bin=%s)", orUnset(v))
+		c.Hint = "start the server with --log-bin and a non-zero --server-id"
+	}
+	return c
+}
+
+func checkBinlogFormat(f *facts) Check {
+	c := Check{Name: "binlog_format"}
+	if v := f.vars["binlog_format"]; v == "ROW" {
+		c.Status, c.Detail = StatusOK, "ROW"
+	} else {
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("%s, but row changes are only logged in ROW format", orUnset(v))
+		c.Hint = "set binlog_format=ROW in the server config; SET GLOBAL only affects new sessions"
+	}
+	return c
+}
+
+func checkRowImage(f *facts) Check {
+	c := Check{Name: "binlog_row_image"}
+	switch v := f.vars["binlog_row_image"]; v {
+	case "FULL":
+		c.Status, c.Detail = StatusOK, "FULL"
+	default:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("%s: rows leave out unchanged columns and are flagged partial", orUnset(v))
+		c.Hint = "set binlog_row_image=FULL for complete before and after images"
+	}
+	return c
+}
+
+func checkRowMetadata(f *facts) Check {
+	c := Check{Name: "binlog_row_metadata"}
+	v, ok := f.vars["binlog_row_metadata"]
+	switch {
+	case v == "FULL":
+		c.Status, c.Detail = StatusOK, "FULL"
+	case !ok:
+		c.Status, c.Detail = StatusWarn, "not supported by this server: column names are read from information_schema, which can race with DDL"
+		c.Hint = "upgrade to MySQL 8.0.1+ or MariaDB 10.5+ and set binlog_row_metadata=FULL"
+	default:
+		c.Status, c.Detail = StatusWarn, v+": column names are read from information_schema, which can race with DDL"
+		c.Hint = "set binlog_row_metadata=FULL"
+	}
+	return c
+}
+
+func checkGTID(f *facts) Check {
+	c := Check{Name: "gtid"}
+	switch {
+	case f.dbType == model.MariaDB:
+		c.Status, c.Detail = StatusOK, "MariaDB always logs GTIDs"
+	case f.vars["gtid_mode"] == "ON":
+		c.Status, c.Detail = StatusOK, "gtid_mode=ON"
+	default:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("gtid_mode=%s: offsets are file positions, which do not survive a failover", orUnset(f.vars["gtid_mode"]))
+		c.Hint = "set enforce_gtid_consistency=ON and gtid_mode=ON"
+	}
+	return c
+}
+
+func checkRetention(f *facts, downtime time.Duration) Check {
+	c := Check{Name: "binlog_retention"}
+
+	var retention time.Duration
+	if s, _ := strconv.ParseInt(f.vars["binlog_expire_logs_seconds"], 10, 64); s > 0 {
+		retention = time.Duration(s) * time.Second
+	} else if d, _ := strconv.ParseFloat(f.vars["expire_logs_days"], 64); d > 0 {
+		retention = time.Duration(d * float64(24*time.Hour))
+	}
+
+	switch {
+	case retention == 0:
+		c.Status, c.Detail = StatusOK, "binlogs are not purged automatically"
+	case retention < downtime:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("binlogs are kept for %v, less than the expected downtime of %v", retention, downtime)
+		c.Hint = fmt.Sprintf("raise binlog_expire_logs_seconds to at least %d, or choose database.on_purged", int64(downtime.Seconds()))
+	default:
+		c.Status, c.Detail = StatusOK, fmt.Sprintf("binlogs are kept for %v", retention)
+	}
+	return c
+}
+
+var grantLine = regexp.MustCompile(`(?i)^GRANT (.+?) ON (\S+) TO `)
+
+func checkPrivileges(f *facts, schema string, tables []config.Table) Check {
+	c := Check{Name: "privileges"}
+	if f.grantsErr != nil {
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("could not read grants: %v", f.grantsErr)
+		return c
+	}
+
+	// privs maps an object (*.*, schema.*, schema.table) to its privileges
+	privs := map[string]map[string]bool{}
+	for _, g := range f.grants {
+		m := grantLine.FindStringSubmatch(g)
+		if m == nil {
+			continue
+		}
+		obj := strings.ReplaceAll(m[2], "`", "")
+		if privs[obj] == nil {
+			privs[obj] = map[string]bool{}
+		}
+		for _, p := range strings.Split(m[1], ",") {
+			privs[obj][strings.ToUpper(strings.TrimSpace(p))] = true
+		}
+	}
+	has := func(obj, priv string) bool {
+		return privs[obj][priv] || privs[obj]["ALL PRIVILEGES"] || privs[obj]["ALL"]
+	}
+
+	var missing []string
+	if !has("*.*", "REPLICATION SLAVE") {
+		missing = append(missing, "REPLICATION SLAVE")
+	}
+	// MariaDB 10.5 split REPLICATION CLIENT into BINLOG MONITOR and others
+	if !has("*.*", "REPLICATION CLIENT") && !has("*.*", "BINLOG MONITOR") {
+		missing = append(missing, "REPLICATION CLIENT")
+	}
+	for _, t := range tables {
+		if !has("*.*", "SELECT") && !has(schema+".*", "SELECT") && !has(schema+"."+t.Name, "SELECT") {
+			missing = append(missing, "SELECT on "+schema+"."+t.Name)
+		}
+	}
+
+	if len(missing) == 0 {
+		c.Status, c.Detail = StatusOK, "REPLICATION SLAVE, REPLICATION CLIENT and SELECT granted"
+		return c
+	}
+	c.Status, c.Detail = StatusFail, "missing "+strings.Join(missing, ", ")
+	c.Hint = fmt.Sprintf("GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO <user>; GRANT SELECT ON %s.* TO <user> (privileges granted through roles are not detected)", schema)
+	return c
+}
+
+func checkServerID(f *facts) Check {
+	c := Check{Name: "server_id"}
+	id, _ := strconv.ParseUint(f.vars["server_id"], 10, 32)
+	if id == 0 {
+		c.Status, c.Detail = StatusFail, "server_id is 0, so the server refuses replicas"
+		c.Hint = "set server_id to a non-zero value unique in the replication topology"
+		return c
+	}
+
+	if f.replicasErr != nil {
+		c.Status, c.Detail = StatusOK, fmt.Sprintf("server_id=%d (could not list replicas: %v)", id, f.replicasErr)
+		return c
+	}
+	seen := map[uint32]bool{uint32(id): true}
+	for _, r := range f.replicaIDs {
+		if seen[r] {
+			c.Status, c.Detail = StatusWarn, fmt.Sprintf("server_id %d is used by more than one server", r)
+			c.Hint = "give every replica a unique server_id; replicas sharing one disconnect each other"
+			return c
+		}
+		seen[r] = true
+	}
+	c.Status = StatusOK
+	c.Detail = fmt.Sprintf("server_id=%d, %d replicas registered; tabellarius picks a fresh id per connection", id, len(f.replicaIDs))
+	return c
+}
+
+// checkTable checks that table exists and, when pk is set, that pk is its
+// primary key.
+func checkTable(f *facts, schema, table, pk string) Check {
+	c := Check{Name: "table " + schema + "." + table}
+	cols, ok := f.columns[table]
+	switch {
+	case !ok && pk == "":
+		c.Status, c.Detail = StatusFail, "cdc_log table does not exist"
+		c.Hint = "run cdc-cli --mode=init --apply"
+	case !ok:
+		c.Status, c.Detail = StatusFail, "table does not exist"
+		c.Hint = "create the table or remove it from tables"
+	case pk == "":
+		c.Status, c.Detail = StatusOK, "exists"
+	case !slices.Contains(cols, pk):
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("pk column %s does not exist", pk)
+		c.Hint = "set pk to one of " + strings.Join(cols, ", ")
+	case !slices.Equal(f.primary[table], []string{pk}):
+		primary := strings.Join(f.primary[table], ", ")
+		if primary == "" {
+			primary = "none"
+		}
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("pk %s is not the primary key (%s)", pk, primary)
+		c.Hint = "use the primary key column, or make sure pk is unique and not null"
+	default:
+		c.Status, c.Detail = StatusOK, "pk "+pk
+	}
+	return c
+}
+
+func orUnset(v string) string {
+	if v == "" {
+		return "unset"
+	}
+	return v
+}
+
+// Failed counts the checks that failed.
+func Failed(checks []Check) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+// PrintChecks writes one line per check, followed by its hint if any.
+func PrintChecks(w io.Writer, checks []Check) {
+	for _, c := range checks {
+		fmt.Fprintf(w, "[%s] %s: %s\n", c.Status, c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(w, "       hint: %s\n", c.Hint)
+		}
+	}
+}
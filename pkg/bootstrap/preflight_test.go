@@ -0,0 +1,139 @@
+package bootstrap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func readyFacts() *facts {
+	return &facts{
+		dbType: model.MySQL,
+		vars: map[string]string{
+			"log_bin":                    "ON",
+			"binlog_format":              "ROW",
+			"binlog_row_image":           "FULL",
+			"binlog_row_metadata":        "FULL",
+			"gtid_mode":                  "ON",
+			"binlog_expire_logs_seconds": "2592000",
+			"expire_logs_days":           "0",
+			"server_id":                  "1",
+		},
+		grants: []string{
+			"GRANT SELECT, REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO `cdc`@`%`",
+		},
+		replicaIDs: []uint32{2, 3},
+		columns: map[string][]string{
+			"cdc_log": {"id", "tx_id"},
+			"users":   {"id", "email"},
+		},
+		primary: map[string][]string{
+			"cdc_log": {"id"},
+			"users":   {"id"},
+		},
+	}
+}
+
+func preflightConfig() *config.Config {
+	cfg := &config.Config{Tables: []config.Table{{Name: "users", PK: "id"}}}
+	cfg.Database.Schema = "shop"
+	cfg.CdcLog.Table = "cdc_log"
+	return cfg
+}
+
+func TestEvaluate_Ready(t *testing.T) {
+	checks := evaluate(readyFacts(), preflightConfig(), PreflightOptions{MaxDowntime: 24 * time.Hour})
+	for _, c := range checks {
+		if c.Status != StatusOK {
+			t.Fatalf("%s: expected OK, got %s (%s)", c.Name, c.Status, c.Detail)
+		}
+	}
+	if n := len(checks); n != 10 {
+		t.Fatalf("expected 10 checks, got %d", n)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *facts, cfg *config.Config)
+		check  string
+		status Status
+	}{
+		{"log_bin off", func(f *facts, _ *config.Config) { f.vars["log_bin"] = "OFF" }, "log_bin", StatusFail},
+		{"statement format", func(f *facts, _ *config.Config) { f.vars["binlog_format"] = "MIXED" }, "binlog_format", StatusFail},
+		{"minimal image", func(f *facts, _ *config.Config) { f.vars["binlog_row_image"] = "MINIMAL" }, "binlog_row_image", StatusWarn},
+		{"minimal metadata", func(f *facts, _ *config.Config) { f.vars["binlog_row_metadata"] = "MINIMAL" }, "binlog_row_metadata", StatusWarn},
+		{"no metadata support", func(f *facts, _ *config.Config) { delete(f.vars, "binlog_row_metadata") }, "binlog_row_metadata", StatusWarn},
+		{"gtid off", func(f *facts, _ *config.Config) { f.vars["gtid_mode"] = "OFF" }, "gtid", StatusWarn},
+		{"mariadb gtid", func(f *facts, _ *config.Config) {
+			f.dbType = model.MariaDB
+			delete(f.vars, "gtid_mode")
+		}, "gtid", StatusOK},
+		{"short retention", func(f *facts, _ *config.Config) { f.vars["binlog_expire_logs_seconds"] = "3600" }, "binlog_retention", StatusWarn},
+		{"retention in days", func(f *facts, _ *config.Config) {
+			delete(f.vars, "binlog_expire_logs_seconds")
+			f.vars["expire_logs_days"] = "0.5"
+		}, "binlog_retention", StatusWarn},
+		{"never purged", func(f *facts, _ *config.Config) { f.vars["binlog_expire_logs_seconds"] = "0" }, "binlog_retention", StatusOK},
+		{"no replication grant", func(f *facts, _ *config.Config) {
+			f.grants = []string{"GRANT SELECT ON `shop`.* TO `cdc`@`%`"}
+		}, "privileges", StatusFail},
+		{"all privileges", func(f *facts, _ *config.Config) {
+			f.grants = []string{"GRANT ALL PRIVILEGES ON *.* TO `root`@`%` WITH GRANT OPTION"}
+		}, "privileges", StatusOK},
+		{"mariadb binlog monitor", func(f *facts, _ *config.Config) {
+			f.grants = []string{
+				"GRANT REPLICATION SLAVE, BINLOG MONITOR ON *.* TO `cdc`@`%`",
+				"GRANT SELECT ON `shop`.`users` TO `cdc`@`%`",
+			}
+		}, "privileges", StatusOK},
+		{"select on another table", func(f *facts, _ *config.Config) {
+			f.grants = []string{
+				"GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO `cdc`@`%`",
+				"GRANT SELECT ON `shop`.`orders` TO `cdc`@`%`",
+			}
+		}, "privileges", StatusFail},
+		{"grants unreadable", func(f *facts, _ *config.Config) { f.grantsErr = errors.New("denied") }, "privileges", StatusWarn},
+		{"server_id 0", func(f *facts, _ *config.Config) { f.vars["server_id"] = "0" }, "server_id", StatusFail},
+		{"replica shares server_id", func(f *facts, _ *config.Config) { f.replicaIDs = []uint32{1} }, "server_id", StatusWarn},
+		{"replicas share server_id", func(f *facts, _ *config.Config) { f.replicaIDs = []uint32{2, 2} }, "server_id", StatusWarn},
+		{"missing cdc_log", func(f *facts, _ *config.Config) { delete(f.columns, "cdc_log") }, "table shop.cdc_log", StatusFail},
+		{"missing table", func(f *facts, _ *config.Config) { delete(f.columns, "users") }, "table shop.users", StatusFail},
+		{"missing pk column", func(_ *facts, cfg *config.Config) { cfg.Tables[0].PK = "uid" }, "table shop.users", StatusFail},
+		{"pk not primary", func(_ *facts, cfg *config.Config) { cfg.Tables[0].PK = "email" }, "table shop.users", StatusWarn},
+		{"composite primary key", func(f *facts, _ *config.Config) { f.primary["users"] = []string{"id", "email"} }, "table shop.users", StatusWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, cfg := readyFacts(), preflightConfig()
+			tt.modify(f, cfg)
+
+			checks := evaluate(f, cfg, PreflightOptions{MaxDowntime: 24 * time.Hour})
+			for _, c := range checks {
+				if c.Name != tt.check {
+					continue
+				}
+				if c.Status != tt.status {
+					t.Fatalf("expected %s, got %s (%s)", tt.status, c.Status, c.Detail)
+				}
+				if c.Status != StatusOK && c.Hint == "" && tt.check != "privileges" {
+					t.Fatalf("expected a hint for %s", c.Detail)
+				}
+				return
+			}
+			t.Fatalf("no check named %q", tt.check)
+		})
+	}
+}
+
+func TestFailed(t *testing.T) {
+	checks := []Check{{Status: StatusOK}, {Status: StatusWarn}, {Status: StatusFail}, {Status: StatusFail}}
+	if n := Failed(checks); n != 2 {
+		t.Fatalf("expected 2 failed, got %d", n)
+	}
+}
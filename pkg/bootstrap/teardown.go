@@ -0,0 +1,116 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+)
+
+const (
+	RemoveTable = "table"
+	RemoveFile  = "file"
+)
+
+// Removal is one thing teardown removes. Rows and Size say how much data
+// goes with it.
+type Removal struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Exists bool   `json:"exists"`
+	Rows   int64  `json:"rows,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// HasData reports whether removing r loses anything beyond empty metadata.
+func (r Removal) HasData() bool {
+	return r.Exists && (r.Rows > 0 || r.Size > 0)
+}
+
+// PlanTeardown lists what Init created for cfg, the cdc_log table, followed
+// by the given pipeline files such as its checkpoints.
+func PlanTeardown(ctx context.Context, db *sql.DB, cfg *config.Config, files []string) ([]Removal, error) {
+	table := cfg.CdcLog.Table
+	ok, err := ExistsTable(db, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect cdc_log table: %w", err)
+	}
+	r := Removal{Kind: RemoveTable, Target: table, Exists: ok}
+	if ok {
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteIdent(table)).Scan(&r.Rows); err != nil {
+			return nil, fmt.Errorf("count %s rows: %w", table, err)
+		}
+	}
+
+	fileRemovals, err := planFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Removal{r}, fileRemovals...), nil
+}
+
+func planFiles(paths []string) ([]Removal, error) {
+	var out []Removal
+	for _, path := range paths {
+		r := Removal{Kind: RemoveFile, Target: path}
+		fi, err := os.Stat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			r.Exists = true
+			r.Size = fi.Size()
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Teardown carries out a plan from PlanTeardown in order, skipping what no
+// longer exists.
+func Teardown(ctx context.Context, db *sql.DB, plan []Removal) error {
+	for _, r := range plan {
+		if !r.Exists {
+			continue
+		}
+		switch r.Kind {
+		case RemoveTable:
+			if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdent(r.Target)); err != nil {
+				return fmt.Errorf("drop table %s: %w", r.Target, err)
+			}
+		case RemoveFile:
+			if err := os.Remove(r.Target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown removal kind: %s", r.Kind)
+		}
+		fmt.Printf("[cli] removed %s %s\n", r.Kind, r.Target)
+	}
+	return nil
+}
+
+// PrintPlan writes one line per removal, marking those that lose data.
+func PrintPlan(w io.Writer, plan []Removal) {
+	for _, r := range plan {
+		switch {
+		case !r.Exists:
+			fmt.Fprintf(w, "[SKIP] %s %s (missing)\n", r.Kind, r.Target)
+		case r.Kind == RemoveTable:
+			fmt.Fprintf(w, "[DROP] %s %s (%d rows)\n", r.Kind, r.Target, r.Rows)
+		default:
+			fmt.Fprintf(w, "[DROP] %s %s (%d bytes)\n", r.Kind, r.Target, r.Size)
+		}
+	}
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,60 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanFiles(t *testing.T) {
+	dir := t.TempDir()
+	offset := filepath.Join(dir, "offset.json.binlog")
+	empty := filepath.Join(dir, "offset.json.empty")
+	if err := os.WriteFile(offset, []byte(`{"file":"bin.000001","pos":4}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := planFiles([]string{filepath.Join(dir, "offset.json"), offset, empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan[0].Exists || !plan[1].HasData() || !plan[2].Exists || plan[2].HasData() {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	if err := Teardown(context.Background(), nil, plan); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{offset, empty} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", path, err)
+		}
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	var buf bytes.Buffer
+	PrintPlan(&buf, []Removal{
+		{Kind: RemoveTable, Target: "cdc_log", Exists: true, Rows: 12},
+		{Kind: RemoveFile, Target: "offset.json", Exists: true, Size: 40},
+		{Kind: RemoveFile, Target: "offset.json.binlog"},
+	})
+
+	want := "[DROP] table cdc_log (12 rows)\n" +
+		"[DROP] file offset.json (40 bytes)\n" +
+		"[SKIP] file offset.json.binlog (missing)\n"
+	if buf.String() != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	if got := quoteIdent("cdc`log"); got != "`cdc``log`" {
+		t.Fatalf("unexpected quoting %s", got)
+	}
+}
@@ -1,10 +1,19 @@
 package config
 
 import (
+	"bytes"
+	_ "embed"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-sql-driver/mysql"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,50 +21,252 @@ type Database struct {
 	Type     model.DatabaseType `yaml:"type"`
 	Schema   string             `yaml:"schema"`
 	User     string             `yaml:"user"`
-	Password string             `yaml:"password"`
-	Host     string             `yaml:"host"`
-	Port     int                `yaml:"port"`
+	Password Secret             `yaml:"password"`
+	// PasswordFile reads the password from a mounted secret instead.
+	PasswordFile string `yaml:"password_file"`
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`
+	// Socket connects to MySQL/MariaDB over a unix socket instead of
+	// host and port.
+	Socket string `yaml:"socket"`
+	TLS    TLS    `yaml:"tls"`
+
+	// OnPurged decides what happens when the committed offset points at a
+	// binlog the server has already purged: fail (default), earliest or
+	// snapshot.
+	OnPurged string `yaml:"on_purged"`
+
+	// Statements attaches the SQL statement behind row changes, when the
+	// server logs it (binlog_rows_query_log_events on MySQL,
+	// binlog_annotate_row_events on MariaDB).
+	Statements Statements `yaml:"statements"`
+}
+
+// Statements controls capturing of originating SQL statements. MaxLength
+// truncates them (0 keeps them whole) and Redact replaces literals with ?.
+type Statements struct {
+	Capture   bool `yaml:"capture"`
+	MaxLength int  `yaml:"max_length"`
+	Redact    bool `yaml:"redact"`
 }
 
+const (
+	OnPurgedFail     = "fail"
+	OnPurgedEarliest = "earliest"
+	OnPurgedSnapshot = "snapshot"
+)
+
 type Table struct {
 	Name string `yaml:"name"`
 	PK   string `yaml:"pk"`
+	// Snapshot publishes the table's current rows when a reload adds it to
+	// a running pipeline.
+	Snapshot bool `yaml:"snapshot"`
 }
 
 type CDCServer struct {
-	OffsetFile    string `yaml:"offset_file"`
-	PublisherAddr string `yaml:"publisher_addr"`
+	OffsetFile    string   `yaml:"offset_file"`
+	PublisherAddr string   `yaml:"publisher_addr"`
+	Buffer        TxBuffer `yaml:"buffer"`
+	Fragment      Fragment `yaml:"fragment"`
+}
+
+// Fragment publishes transactions with more than MaxRows rows or MaxBytes
+// bytes in ordered parts instead of one message. Zero disables a limit.
+type Fragment struct {
+	MaxRows  int   `yaml:"max_rows"`
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// TxBuffer bounds the memory used by transactions awaiting their commit;
+// larger transactions spill to SpillDir.
+type TxBuffer struct {
+	MaxMemoryBytes int64  `yaml:"max_memory_bytes"`
+	SpillDir       string `yaml:"spill_dir"`
+}
+
+const (
+	SinkCursus = "cursus"
+	SinkFile   = "file"
+	SinkHTTP   = "http"
+)
+
+type Sink struct {
+	Type string `yaml:"type"`
+	// Format selects the message encoding for message-based sinks
+	// (json, debezium, avro, protobuf, cloudevents).
+	Format          string         `yaml:"format"`
+	ServerName      string         `yaml:"server_name"`
+	SchemaRegistry  SchemaRegistry `yaml:"schema_registry"`
+	CloudEventsMode string         `yaml:"cloudevents_mode"`
+	// CanalTxMarkers adds transaction begin and end messages to canal-json.
+	CanalTxMarkers bool     `yaml:"canal_tx_markers"`
+	File           FileSink `yaml:"file"`
+	HTTP           HTTPSink `yaml:"http"`
+}
+
+// HTTPSink POSTs every encoded message to URL.
+type HTTPSink struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+type SchemaRegistry struct {
+	URL string `yaml:"url"`
+}
+
+// FileSink writes row changes as Parquet files partitioned by table and hour,
+// either to a local directory (Path) or to an S3-compatible bucket (S3).
+type FileSink struct {
+	Path         string        `yaml:"path"`
+	MaxFileBytes int64         `yaml:"max_file_bytes"`
+	RollInterval time.Duration `yaml:"roll_interval"`
+	S3           S3Store       `yaml:"s3"`
+}
+
+type S3Store struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey Secret `yaml:"secret_key"`
+	// SecretKeyFile reads the secret key from a mounted secret instead.
+	SecretKeyFile string `yaml:"secret_key_file"`
 }
 
 type Config struct {
+	// Name identifies the pipeline in logs and the admin API.
+	Name     string   `yaml:"name"`
 	Database Database `yaml:"database"`
 	CdcLog   struct {
 		Table string `yaml:"table"`
 	} `yaml:"cdc_log"`
 	Tables    []Table   `yaml:"tables"`
 	CDCServer CDCServer `yaml:"cdc_server"`
+	Sink      Sink      `yaml:"sink"`
 }
 
+// JSONSchema describes the config file for editors and linters.
+//
+//go:embed config.schema.json
+var JSONSchema []byte
+
+// Load reads the config at path, expanding environment variables and
+// secret files, rejecting unknown keys and filling in defaults. Callers
+// should Validate the result before using it.
 func Load(path string) (*Config, error) {
-	b, err := os.ReadFile(path)
+	doc, err := readDoc(path)
 	if err != nil {
 		return nil, err
 	}
+	return loadConfig(path, doc)
+}
+
+func loadConfig(path string, doc *yaml.Node) (*Config, error) {
 	var c Config
-	return &c, yaml.Unmarshal(b, &c)
+	if err := decodeStrict(doc, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.applyDefaults()
+	return &c, nil
 }
 
-func (c *Config) DSN() string {
+// readDoc parses the YAML at path and expands environment variables in it.
+func readDoc(path string) (*yaml.Node, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := interpolate(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// decodeStrict decodes doc into out, rejecting unknown keys. An empty
+// document leaves out untouched.
+func decodeStrict(doc *yaml.Node, out any) error {
+	if doc.Kind == 0 {
+		return nil
+	}
+
+	// re-encode so unknown keys are still rejected after interpolation
+	expanded, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(expanded))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// DSN is the driver connection string for the database.
+func (c *Config) DSN() (string, error) {
 	db := c.Database
 
 	switch db.Type {
 	case model.MySQL, model.MariaDB:
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true", db.User, db.Password, db.Host, db.Port, db.Schema)
+		// the driver's own formatter copes with any password and with
+		// IPv6 hosts, and ParseDSN reads the result back unchanged
+		mc := mysql.NewConfig()
+		mc.User = db.User
+		mc.Passwd = db.Password.Value()
+		mc.DBName = db.Schema
+		mc.ParseTime = true
+		if db.Socket != "" {
+			mc.Net, mc.Addr = "unix", db.Socket
+		} else {
+			mc.Net, mc.Addr = "tcp", net.JoinHostPort(db.Host, strconv.Itoa(db.Port))
+		}
+		if db.TLS.enabled() {
+			name, err := registerTLS(db.TLS, db.Host)
+			if err != nil {
+				return "", fmt.Errorf("database.tls: %w", err)
+			}
+			mc.TLSConfig = name
+		}
+		return mc.FormatDSN(), nil
 
 	case model.Postgres:
-		return fmt.Sprintf("postgres://%s:%s@%s:%d/%s", db.User, db.Password, db.Host, db.Port, db.Schema)
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(db.User, db.Password.Value()),
+			Host:   net.JoinHostPort(db.Host, strconv.Itoa(db.Port)),
+			Path:   "/" + db.Schema,
+		}
+		if db.TLS.Mode != "" {
+			q := url.Values{}
+			q.Set("sslmode", postgresSSLModes[db.TLS.Mode])
+			for k, v := range map[string]string{"sslrootcert": db.TLS.CA, "sslcert": db.TLS.Cert, "sslkey": db.TLS.Key} {
+				if v != "" {
+					q.Set(k, v)
+				}
+			}
+			u.RawQuery = q.Encode()
+		}
+		return u.String(), nil
 
 	default:
-		panic("unsupported database type: " + string(db.Type))
+		return "", errors.New("unsupported database type: " + string(db.Type))
 	}
 }
+
+var postgresSSLModes = map[string]string{
+	TLSDisabled:       "disable",
+	TLSRequired:       "require",
+	TLSVerifyCA:       "verify-ca",
+	TLSVerifyIdentity: "verify-full",
+}
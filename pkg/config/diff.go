@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Changes is what a reload changes in a running pipeline.
+type Changes struct {
+	AddedTables   []Table
+	RemovedTables []Table
+	// UpdatedTables are tables whose pk or snapshot setting changed.
+	UpdatedTables []Table
+	Statements    bool
+	Sink          bool
+}
+
+func (c Changes) Empty() bool {
+	return !c.Tables() && !c.Statements && !c.Sink
+}
+
+// Tables reports whether the table list changed.
+func (c Changes) Tables() bool {
+	return len(c.AddedTables)+len(c.RemovedTables)+len(c.UpdatedTables) > 0
+}
+
+func (c Changes) String() string {
+	var parts []string
+	for _, t := range c.AddedTables {
+		parts = append(parts, "+table "+t.Name)
+	}
+	for _, t := range c.RemovedTables {
+		parts = append(parts, "-table "+t.Name)
+	}
+	for _, t := range c.UpdatedTables {
+		parts = append(parts, "~table "+t.Name)
+	}
+	if c.Statements {
+		parts = append(parts, "database.statements")
+	}
+	if c.Sink {
+		parts = append(parts, "sink")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// UnsafeChangeError lists settings a running pipeline cannot pick up.
+type UnsafeChangeError []string
+
+func (e UnsafeChangeError) Error() string {
+	return "restart required to change " + strings.Join(e, ", ")
+}
+
+// Diff compares a pipeline's next config with the running one. The table
+// list, database.statements and the sink can change live; any other change
+// is returned as an UnsafeChangeError.
+func Diff(cur, next *Config) (Changes, error) {
+	var unsafe UnsafeChangeError
+	if cur.Name != next.Name {
+		unsafe = append(unsafe, "name")
+	}
+	curDB, nextDB := cur.Database, next.Database
+	curDB.Statements, nextDB.Statements = Statements{}, Statements{}
+	unsafe = append(unsafe, changedFields("database", curDB, nextDB)...)
+	unsafe = append(unsafe, changedFields("cdc_log", cur.CdcLog, next.CdcLog)...)
+	unsafe = append(unsafe, changedFields("cdc_server", cur.CDCServer, next.CDCServer)...)
+	if len(unsafe) > 0 {
+		return Changes{}, unsafe
+	}
+
+	var ch Changes
+	running := map[string]Table{}
+	for _, t := range cur.Tables {
+		running[t.Name] = t
+	}
+	for _, t := range next.Tables {
+		old, ok := running[t.Name]
+		switch {
+		case !ok:
+			ch.AddedTables = append(ch.AddedTables, t)
+		case old != t:
+			ch.UpdatedTables = append(ch.UpdatedTables, t)
+		}
+		delete(running, t.Name)
+	}
+	for _, t := range cur.Tables {
+		if _, removed := running[t.Name]; removed {
+			ch.RemovedTables = append(ch.RemovedTables, t)
+		}
+	}
+
+	ch.Statements = cur.Database.Statements != next.Database.Statements
+	ch.Sink = !reflect.DeepEqual(cur.Sink, next.Sink)
+	return ch, nil
+}
+
+// changedFields names the yaml keys of the struct fields that differ
+// between a and b.
+func changedFields(prefix string, a, b any) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var out []string
+	for i := 0; i < va.NumField(); i++ {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		key := strings.Split(va.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		out = append(out, fmt.Sprintf("%s.%s", prefix, key))
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+		unsafe []string
+	}{
+		{name: "unchanged", modify: func(c *Config) {}},
+		{
+			name: "tables",
+			modify: func(c *Config) {
+				c.Tables = []Table{{Name: "users", PK: "uid"}, {Name: "orders", PK: "id", Snapshot: true}}
+			},
+			want: "+table orders, ~table users",
+		},
+		{
+			name:   "removed table, statements and sink",
+			modify: func(c *Config) { c.Tables = nil; c.Database.Statements.Capture = true; c.Sink.Format = "debezium" },
+			want:   "-table users, database.statements, sink",
+		},
+		{
+			name: "unsafe",
+			modify: func(c *Config) {
+				c.Database.Host = "replica"
+				c.CDCServer.OffsetFile = "other.json"
+				c.Tables = nil
+			},
+			unsafe: []string{"database.host", "cdc_server.offset_file"},
+		},
+	}
+
+	for _, tt := range tests {
+		cur, next := validConfig(), validConfig()
+		tt.modify(next)
+
+		ch, err := Diff(cur, next)
+		var unsafe UnsafeChangeError
+		if errors.As(err, &unsafe) {
+			if fmt.Sprint([]string(unsafe)) != fmt.Sprint(tt.unsafe) {
+				t.Fatalf("%s: expected unsafe %v, got %v", tt.name, tt.unsafe, unsafe)
+			}
+			continue
+		}
+		if err != nil || tt.unsafe != nil {
+			t.Fatalf("%s: expected unsafe %v, got %v", tt.name, tt.unsafe, err)
+		}
+		if ch.String() != tt.want || ch.Empty() != (tt.want == "") {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, ch)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, "a: 1\n")
+	changed := make(chan struct{}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, path, 5*time.Millisecond, func() { changed <- struct{}{} })
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("unchanged file reported")
+	default:
+	}
+
+	if err := os.WriteFile(path, []byte("a: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not reported")
+	}
+}
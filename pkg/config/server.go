@@ -0,0 +1,160 @@
+package config
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPipeline names the pipeline of a single-pipeline config file.
+const DefaultPipeline = "default"
+
+// Server is what one cdc-server process runs: the pipelines of a file that
+// lists them under pipelines:, or the one pipeline of a plain config file.
+type Server struct {
+	Admin     Admin    `yaml:"admin"`
+	Pipelines []Config `yaml:"pipelines"`
+
+	// single is set for a plain config file, whose problems are reported
+	// without a pipelines[i] prefix.
+	single bool
+}
+
+// Admin serves pipeline status over HTTP at Addr, e.g. ":8081". Empty
+// disables it.
+type Admin struct {
+	Addr string `yaml:"addr"`
+}
+
+// ServerJSONSchema describes multi-pipeline config files.
+//
+//go:embed server.schema.json
+var ServerJSONSchema []byte
+
+var pipelineName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// LoadServer reads a multi-pipeline file, or a plain config file as a single
+// pipeline named DefaultPipeline unless it sets a name.
+func LoadServer(path string) (*Server, error) {
+	doc, err := readDoc(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasKey(doc, "pipelines") {
+		c, err := loadConfig(path, doc)
+		if err != nil {
+			return nil, err
+		}
+		if c.Name == "" {
+			c.Name = DefaultPipeline
+		}
+		return &Server{Pipelines: []Config{*c}, single: true}, nil
+	}
+
+	var s Server
+	if err := decodeStrict(doc, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range s.Pipelines {
+		p := &s.Pipelines[i]
+		if err := p.resolveSecrets(); err != nil {
+			return nil, fmt.Errorf("%s: pipelines[%d]: %w", path, i, err)
+		}
+		p.applyDefaults()
+	}
+	return &s, nil
+}
+
+// Pipeline returns the pipeline called name, or the only one when name is
+// empty and there is just one.
+func (s *Server) Pipeline(name string) (*Config, error) {
+	names := make([]string, 0, len(s.Pipelines))
+	for i := range s.Pipelines {
+		if s.Pipelines[i].Name == name || (name == "" && len(s.Pipelines) == 1) {
+			return &s.Pipelines[i], nil
+		}
+		names = append(names, s.Pipelines[i].Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("choose a pipeline: %s", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("no pipeline %q, have %s", name, strings.Join(names, ", "))
+}
+
+// hasKey reports whether the document is a mapping with key at the top.
+func hasKey(doc *yaml.Node, key string) bool {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return false
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every pipeline and that they do not share names or the
+// files they keep state in, reporting all problems at once.
+func (s *Server) Validate() error {
+	var errs ValidationError
+	add := func(path, msg string, args ...any) {
+		errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	if len(s.Pipelines) == 0 {
+		add("pipelines", "at least one pipeline is required")
+	}
+
+	names := map[string]int{}
+	offsets := map[string]int{}
+	spills := map[string]int{}
+	for i, p := range s.Pipelines {
+		prefix := fmt.Sprintf("pipelines[%d].", i)
+		if s.single {
+			prefix = ""
+		}
+
+		switch first, dup := names[p.Name]; {
+		case p.Name == "":
+			add(prefix+"name", "is required")
+		case !pipelineName.MatchString(p.Name):
+			add(prefix+"name", "%q may only contain letters, digits, '_', '.' and '-'", p.Name)
+		case dup:
+			add(prefix+"name", "duplicates pipelines[%d]", first)
+		default:
+			names[p.Name] = i
+		}
+		if first, dup := offsets[p.CDCServer.OffsetFile]; dup {
+			add(prefix+"cdc_server.offset_file", "is shared with pipelines[%d]", first)
+		} else if p.CDCServer.OffsetFile != "" {
+			offsets[p.CDCServer.OffsetFile] = i
+		}
+		if first, dup := spills[p.CDCServer.Buffer.SpillDir]; dup {
+			add(prefix+"cdc_server.buffer.spill_dir", "is shared with pipelines[%d]", first)
+		} else if p.CDCServer.Buffer.SpillDir != "" {
+			spills[p.CDCServer.Buffer.SpillDir] = i
+		}
+
+		var verr ValidationError
+		if err := p.Validate(); errors.As(err, &verr) {
+			for _, e := range verr {
+				add(prefix+e.Path, "%s", e.Message)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
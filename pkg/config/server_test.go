@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+const pipelineYAML = `
+  - name: %s
+    database: {type: mysql, schema: %s, user: root, password: "${DB_PASSWORD}", host: db, port: 3306}
+    cdc_log: {table: cdc_log}
+    tables: [{name: users, pk: id}]
+    cdc_server: {offset_file: %s, publisher_addr: "broker:9000"}
+`
+
+func TestLoadServer_Pipelines(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "s3cr3t")
+	path := writeConfig(t, "admin:\n  addr: \":8081\"\npipelines:"+
+		fmt.Sprintf(pipelineYAML, "orders", "shop", "orders.json")+
+		fmt.Sprintf(pipelineYAML, "billing", "billing", "billing.json"))
+
+	s, err := LoadServer(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	if s.Admin.Addr != ":8081" || len(s.Pipelines) != 2 {
+		t.Fatalf("unexpected server config: %+v", s)
+	}
+	p := s.Pipelines[1]
+	if p.Name != "billing" || p.Database.Schema != "billing" || p.Database.Password.Value() != "s3cr3t" {
+		t.Fatalf("pipeline not decoded: %+v", p.Database)
+	}
+	if p.Database.OnPurged != OnPurgedFail || p.Sink.Type != SinkCursus {
+		t.Fatalf("pipeline defaults not applied: %+v", p.Sink)
+	}
+}
+
+func TestServerPipeline(t *testing.T) {
+	s := &Server{Pipelines: []Config{{Name: "orders"}, {Name: "billing"}}}
+
+	if p, err := s.Pipeline("billing"); err != nil || p != &s.Pipelines[1] {
+		t.Fatalf("expected billing, got %v, %v", p, err)
+	}
+	if _, err := s.Pipeline("missing"); err == nil {
+		t.Fatal("expected an error for an unknown pipeline")
+	}
+	if _, err := s.Pipeline(""); err == nil {
+		t.Fatal("expected an error choosing among several pipelines")
+	}
+
+	s.Pipelines = s.Pipelines[:1]
+	if p, err := s.Pipeline(""); err != nil || p.Name != "orders" {
+		t.Fatalf("expected the only pipeline, got %v, %v", p, err)
+	}
+}
+
+func TestLoadServer_SingleFile(t *testing.T) {
+	path := writeConfig(t, `
+database: {type: mysql, schema: shop, user: root, host: db, port: 0}
+cdc_log: {table: cdc_log}
+tables: [{name: users, pk: id}]
+cdc_server: {offset_file: offset.json, publisher_addr: "broker:9000"}
+`)
+
+	s, err := LoadServer(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(s.Pipelines) != 1 || s.Pipelines[0].Name != DefaultPipeline {
+		t.Fatalf("expected one default pipeline, got %+v", s.Pipelines)
+	}
+
+	// problems keep the paths of a plain config file
+	var verr ValidationError
+	if err := s.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].Path != "database.port" {
+		t.Fatalf("expected a database.port problem, got %v", err)
+	}
+}
+
+func TestServerValidate(t *testing.T) {
+	pipeline := func(name, offset string) Config {
+		c := validConfig()
+		c.Name = name
+		c.CDCServer.OffsetFile = offset
+		return *c
+	}
+
+	shared := pipeline("b", "b.json")
+	shared.CDCServer.Buffer.SpillDir = "/var/spill"
+	broken := pipeline("bad name", "a.json")
+	broken.CDCServer.Buffer.SpillDir = "/var/spill"
+	broken.Tables = nil
+
+	s := &Server{Pipelines: []Config{pipeline("a", "a.json"), shared, broken, pipeline("a", "c.json"), pipeline("", "d.json")}}
+	var verr ValidationError
+	if !errors.As(s.Validate(), &verr) {
+		t.Fatal("expected validation errors")
+	}
+	var got []string
+	for _, e := range verr {
+		got = append(got, e.Path)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"pipelines[2].cdc_server.buffer.spill_dir",
+		"pipelines[2].cdc_server.offset_file",
+		"pipelines[2].name",
+		"pipelines[2].tables",
+		"pipelines[3].name",
+		"pipelines[4].name",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected problems at %v, got %v (%v)", want, got, verr)
+	}
+
+	if err := (&Server{}).Validate(); err == nil {
+		t.Fatal("expected an empty server config to be rejected")
+	}
+}
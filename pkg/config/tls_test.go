@@ -0,0 +1,192 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-sql-driver/mysql"
+)
+
+// writeCert writes a self-signed CA certificate for db.internal and its key,
+// returning the certificate and the paths of both files.
+func writeCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		DNSNames:              []string{"db.internal"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certPath, keyPath
+}
+
+func TestTLSConfig_Modes(t *testing.T) {
+	cert, certPath, keyPath := writeCert(t)
+
+	if cfg, err := (TLS{Mode: TLSDisabled, CA: certPath}).Config("db"); err != nil || cfg != nil {
+		t.Fatalf("disabled: expected no config, got %v %v", cfg, err)
+	}
+
+	tests := []struct {
+		mode       string
+		serverName string
+		skipVerify bool
+		wantName   string
+	}{
+		{TLSRequired, "", true, "db"},
+		{TLSVerifyCA, "", true, "db"},
+		{TLSVerifyIdentity, "", false, "db"},
+		{TLSVerifyIdentity, "db.internal", false, "db.internal"},
+	}
+	for _, tt := range tests {
+		cfg, err := TLS{Mode: tt.mode, CA: certPath, Cert: certPath, Key: keyPath, ServerName: tt.serverName}.Config("db")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		if cfg.InsecureSkipVerify != tt.skipVerify || cfg.ServerName != tt.wantName {
+			t.Fatalf("%s: skipVerify=%v serverName=%q", tt.mode, cfg.InsecureSkipVerify, cfg.ServerName)
+		}
+		if cfg.RootCAs == nil || len(cfg.Certificates) != 1 {
+			t.Fatalf("%s: CA or client certificate not loaded", tt.mode)
+		}
+		if (cfg.VerifyConnection != nil) != (tt.mode == TLSVerifyCA) {
+			t.Fatalf("%s: unexpected chain verification hook", tt.mode)
+		}
+	}
+
+	// verify_ca accepts a certificate from the CA whatever its host name
+	cfg, _ := TLS{Mode: TLSVerifyCA, CA: certPath}.Config("elsewhere")
+	if err := cfg.VerifyConnection(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}); err != nil {
+		t.Fatalf("verify_ca rejected a trusted certificate: %v", err)
+	}
+	other, _, _ := writeCert(t)
+	if err := cfg.VerifyConnection(tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}); err == nil {
+		t.Fatal("verify_ca accepted an untrusted certificate")
+	}
+}
+
+func TestTLSConfig_Errors(t *testing.T) {
+	_, certPath, _ := writeCert(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("nope"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		tls  TLS
+		want string
+	}{
+		{"missing ca", TLS{Mode: TLSVerifyCA, CA: "/nonexistent/ca.pem"}, "no such file"},
+		{"empty ca", TLS{Mode: TLSVerifyCA, CA: notPEM}, "no certificates found"},
+		{"bad key pair", TLS{Mode: TLSRequired, Cert: certPath, Key: notPEM}, "PEM data"},
+		{"mode", TLS{Mode: "sometimes"}, "unsupported tls mode"},
+	}
+	for _, tt := range tests {
+		_, err := tt.tls.Config("db")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestDSN_MySQLRoundTrip(t *testing.T) {
+	_, certPath, _ := writeCert(t)
+
+	tests := []struct {
+		name     string
+		db       Database
+		wantNet  string
+		wantAddr string
+	}{
+		{"special password", Database{Password: "p@ss:w/rd?x=1", Host: "db", Port: 3306}, "tcp", "db:3306"},
+		{"ipv6", Database{Password: "pass", Host: "::1", Port: 3307}, "tcp", "[::1]:3307"},
+		{"unix socket", Database{Password: "pass", Socket: "/var/run/mysqld/mysqld.sock"}, "unix", "/var/run/mysqld/mysqld.sock"},
+		{"tls", Database{Password: "pass", Host: "db", Port: 3306, TLS: TLS{Mode: TLSVerifyIdentity, CA: certPath}}, "tcp", "db:3306"},
+	}
+	for _, tt := range tests {
+		db := tt.db
+		db.Type, db.User, db.Schema = model.MySQL, "user", "mydb"
+		cfg := &Config{Database: db}
+
+		dsn, err := cfg.DSN()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		dc, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if dc.User != "user" || dc.Passwd != db.Password.Value() || dc.DBName != "mydb" {
+			t.Fatalf("%s: credentials did not survive: %+v", tt.name, dc)
+		}
+		if dc.Net != tt.wantNet || dc.Addr != tt.wantAddr {
+			t.Fatalf("%s: expected %s(%s), got %s(%s)", tt.name, tt.wantNet, tt.wantAddr, dc.Net, dc.Addr)
+		}
+		if (dc.TLS != nil) != db.TLS.enabled() {
+			t.Fatalf("%s: unexpected tls config %v", tt.name, dc.TLS)
+		}
+		if dc.TLS != nil && (dc.TLS.ServerName != "db" || dc.TLS.RootCAs == nil) {
+			t.Fatalf("%s: tls config not registered as built", tt.name)
+		}
+	}
+}
+
+func TestDSN_PostgresTLS(t *testing.T) {
+	cfg := &Config{
+		Database: Database{
+			Type:     model.Postgres,
+			Schema:   "mydb",
+			User:     "user",
+			Password: "p@ss:word",
+			Host:     "::1",
+			Port:     5432,
+			TLS:      TLS{Mode: TLSVerifyCA, CA: "/etc/ca.pem"},
+		},
+	}
+
+	expected := "postgres://user:p%40ss%3Aword@[::1]:5432/mydb?sslmode=verify-ca&sslrootcert=%2Fetc%2Fca.pem"
+	if dsn, err := cfg.DSN(); err != nil || dsn != expected {
+		t.Fatalf("unexpected dsn:\nexpected=%s\ngot=%s", expected, dsn)
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cursus-io/tabellarius/pkg/format"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// FieldError is a problem with the value at Path, e.g. "tables[1].pk".
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a config.
+type ValidationError []FieldError
+
+func (v ValidationError) Error() string {
+	if len(v) == 1 {
+		return "invalid config: " + v[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config: %d problems", len(v))
+	for _, e := range v {
+		b.WriteString("\n  - ")
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// applyDefaults fills in settings that have a sensible default.
+func (c *Config) applyDefaults() {
+	if c.Database.OnPurged == "" {
+		c.Database.OnPurged = OnPurgedFail
+	}
+	if c.Sink.Type == "" {
+		c.Sink.Type = SinkCursus
+	}
+	if c.Sink.Format == "" && c.Sink.Type != SinkFile {
+		c.Sink.Format = format.JSON
+	}
+	if c.Sink.Format == format.CloudEvents && c.Sink.CloudEventsMode == "" {
+		c.Sink.CloudEventsMode = format.CloudEventsStructured
+	}
+}
+
+// Validate checks the whole config and reports all problems at once.
+func (c *Config) Validate() error {
+	var errs ValidationError
+	add := func(path, msg string, args ...any) {
+		errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	db := c.Database
+	switch db.Type {
+	case model.MySQL, model.MariaDB, model.Postgres:
+	case "":
+		add("database.type", "is required")
+	default:
+		add("database.type", "unsupported type %q (want mysql, mariadb or postgres)", db.Type)
+	}
+	if db.Schema == "" {
+		add("database.schema", "is required")
+	}
+	if db.User == "" {
+		add("database.user", "is required")
+	}
+	if db.Socket != "" {
+		if db.Type == model.Postgres {
+			add("database.socket", "is only supported for mysql and mariadb")
+		}
+	} else {
+		if db.Host == "" {
+			add("database.host", "is required")
+		}
+		if db.Port < 1 || db.Port > 65535 {
+			add("database.port", "must be between 1 and 65535, got %d", db.Port)
+		}
+	}
+	validateTLS(db, add)
+	switch db.OnPurged {
+	case "", OnPurgedFail, OnPurgedEarliest, OnPurgedSnapshot:
+	default:
+		add("database.on_purged", "unsupported policy %q (want fail, earliest or snapshot)", db.OnPurged)
+	}
+	if db.Statements.MaxLength < 0 {
+		add("database.statements.max_length", "must not be negative")
+	}
+
+	if c.CdcLog.Table == "" {
+		add("cdc_log.table", "is required")
+	}
+
+	if len(c.Tables) == 0 {
+		add("tables", "at least one table is required")
+	}
+	seen := map[string]int{}
+	for i, t := range c.Tables {
+		path := fmt.Sprintf("tables[%d]", i)
+		if t.Name == "" {
+			add(path+".name", "is required")
+		} else if first, ok := seen[t.Name]; ok {
+			add(path+".name", "duplicates tables[%d]", first)
+		} else {
+			seen[t.Name] = i
+		}
+		if t.PK == "" {
+			add(path+".pk", "is required")
+		}
+	}
+
+	srv := c.CDCServer
+	if srv.OffsetFile == "" {
+		add("cdc_server.offset_file", "is required")
+	}
+	if srv.Buffer.MaxMemoryBytes < 0 {
+		add("cdc_server.buffer.max_memory_bytes", "must not be negative")
+	}
+	if srv.Fragment.MaxRows < 0 {
+		add("cdc_server.fragment.max_rows", "must not be negative")
+	}
+	if srv.Fragment.MaxBytes < 0 {
+		add("cdc_server.fragment.max_bytes", "must not be negative")
+	}
+
+	c.validateSink(add)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateTLS(db Database, add func(path, msg string, args ...any)) {
+	t := db.TLS
+	switch t.Mode {
+	case "", TLSDisabled, TLSRequired, TLSVerifyCA, TLSVerifyIdentity:
+	default:
+		add("database.tls.mode", "unsupported mode %q (want disabled, required, verify_ca or verify_identity)", t.Mode)
+		return
+	}
+	if !t.enabled() {
+		if t.CA != "" || t.Cert != "" || t.Key != "" || t.ServerName != "" {
+			add("database.tls.mode", "must be set to use the other tls settings")
+		}
+		return
+	}
+	if t.Mode == TLSVerifyIdentity && db.Socket != "" && t.ServerName == "" {
+		add("database.tls.server_name", "is required for verify_identity over a unix socket")
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		add("database.tls", "cert and key must be set together")
+	} else if _, err := t.Config(db.Host); err != nil {
+		add("database.tls", "%v", err)
+	}
+}
+
+func (c *Config) validateSink(add func(path, msg string, args ...any)) {
+	s := c.Sink
+	switch s.Type {
+	case "", SinkCursus:
+		if c.CDCServer.PublisherAddr == "" {
+			add("cdc_server.publisher_addr", "is required for the cursus sink")
+		}
+		if s.Format == format.CloudEvents && s.CloudEventsMode == format.CloudEventsBinary {
+			add("sink.cloudevents_mode", "binary mode needs message headers, which the cursus sink does not support")
+		}
+	case SinkHTTP:
+		if s.HTTP.URL == "" {
+			add("sink.http.url", "is required for the http sink")
+		}
+		if s.HTTP.Timeout < 0 {
+			add("sink.http.timeout", "must not be negative")
+		}
+	case SinkFile:
+		if s.File.Path == "" && s.File.S3.Bucket == "" {
+			add("sink.file", "path or s3.bucket is required for the file sink")
+		}
+		if s.File.MaxFileBytes < 0 {
+			add("sink.file.max_file_bytes", "must not be negative")
+		}
+		if s.File.RollInterval < 0 {
+			add("sink.file.roll_interval", "must not be negative")
+		}
+		return
+	default:
+		add("sink.type", "unsupported sink %q (want cursus, http or file)", s.Type)
+		return
+	}
+
+	switch s.Format {
+	case "", format.JSON, format.Debezium, format.CloudEvents, format.Canal, format.Maxwell:
+	case format.Avro, format.Protobuf:
+		if s.SchemaRegistry.URL == "" {
+			add("sink.schema_registry.url", "is required for the %s format", s.Format)
+		}
+	default:
+		add("sink.format", "unsupported format %q", s.Format)
+	}
+
+	switch s.CloudEventsMode {
+	case "", format.CloudEventsStructured, format.CloudEventsBinary:
+	default:
+		add("sink.cloudevents_mode", "unsupported mode %q (want structured or binary)", s.CloudEventsMode)
+	}
+}
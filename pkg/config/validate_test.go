@@ -0,0 +1,229 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func validConfig() *Config {
+	c := &Config{
+		Database: Database{Type: model.MySQL, Schema: "mydb", User: "root", Host: "localhost", Port: 3306},
+		Tables:   []Table{{Name: "users", PK: "id"}},
+		CDCServer: CDCServer{
+			OffsetFile:    "offset.json",
+			PublisherAddr: "localhost:9092",
+		},
+	}
+	c.CdcLog.Table = "cdc_log"
+	c.applyDefaults()
+	return c
+}
+
+func TestLoad_RejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "database:\n  type: mysql\ntabels:\n  - name: users\n")
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "tabels") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "sink:\n  format: cloudevents\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Database.OnPurged != OnPurgedFail || cfg.Sink.Type != SinkCursus || cfg.Sink.CloudEventsMode != "structured" {
+		t.Fatalf("defaults not applied: %+v %+v", cfg.Database, cfg.Sink)
+	}
+
+	empty, err := Load(writeConfig(t, ""))
+	if err != nil || empty.Sink.Format != "json" {
+		t.Fatalf("expected defaults for an empty file, got %+v %v", empty, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{
+			name: "missing and out of range",
+			modify: func(c *Config) {
+				c.Database.Port = 0
+				c.CdcLog.Table = ""
+				c.Tables = append(c.Tables, Table{Name: "users"})
+			},
+			want: []string{"cdc_log.table", "database.port", "tables[1].name", "tables[1].pk"},
+		},
+		{
+			name: "unknown enums",
+			modify: func(c *Config) {
+				c.Database.Type = "oracle"
+				c.Database.OnPurged = "skip"
+				c.Sink.Format = "xml"
+			},
+			want: []string{"database.on_purged", "database.type", "sink.format"},
+		},
+		{
+			name: "sink requirements",
+			modify: func(c *Config) {
+				c.Sink.Type = SinkHTTP
+				c.Sink.Format = "avro"
+				c.Sink.HTTP.Timeout = -time.Second
+			},
+			want: []string{"sink.http.timeout", "sink.http.url", "sink.schema_registry.url"},
+		},
+		{
+			name: "file sink",
+			modify: func(c *Config) {
+				c.Sink.Type = SinkFile
+			},
+			want: []string{"sink.file"},
+		},
+		{
+			name: "cloudevents binary mode on cursus",
+			modify: func(c *Config) {
+				c.Sink.Format = "cloudevents"
+				c.Sink.CloudEventsMode = "binary"
+			},
+			want: []string{"sink.cloudevents_mode"},
+		},
+		{
+			name: "cloudevents mode unused by the format",
+			modify: func(c *Config) {
+				c.Sink.CloudEventsMode = "binary"
+			},
+		},
+		{
+			name: "unix socket",
+			modify: func(c *Config) {
+				c.Database.Host, c.Database.Port = "", 0
+				c.Database.Socket = "/var/run/mysqld/mysqld.sock"
+			},
+		},
+		{
+			name: "tls",
+			modify: func(c *Config) {
+				c.Database.Socket = "/var/run/mysqld/mysqld.sock"
+				c.Database.TLS = TLS{Mode: TLSVerifyIdentity, CA: "/nonexistent/ca.pem", Cert: "client.pem"}
+			},
+			want: []string{"database.tls", "database.tls.server_name"},
+		},
+		{
+			name: "tls settings without mode",
+			modify: func(c *Config) {
+				c.Database.TLS.CA = "ca.pem"
+			},
+			want: []string{"database.tls.mode"},
+		},
+	}
+
+	for _, tt := range tests {
+		c := validConfig()
+		tt.modify(c)
+
+		err := c.Validate()
+		var got []string
+		var verr ValidationError
+		if errors.As(err, &verr) {
+			for _, e := range verr {
+				got = append(got, e.Path)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error type %T", tt.name, err)
+		}
+		sort.Strings(got)
+
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected problems at %v, got %v (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestValidationError_ListsAll(t *testing.T) {
+	err := ValidationError{{Path: "database.port", Message: "must be between 1 and 65535, got 0"}, {Path: "cdc_log.table", Message: "is required"}}
+	want := "invalid config: 2 problems\n  - database.port: must be between 1 and 65535, got 0\n  - cdc_log.table: is required"
+	if err.Error() != want {
+		t.Fatalf("unexpected message:\n%s", err.Error())
+	}
+}
+
+// TestJSONSchema_MatchesConfig keeps the schemas' properties in sync with the
+// yaml keys of Config and Server.
+func TestJSONSchema_MatchesConfig(t *testing.T) {
+	schemas := map[string]map[string]any{}
+	for name, b := range map[string][]byte{"config.schema.json": JSONSchema, "server.schema.json": ServerJSONSchema} {
+		var schema map[string]any
+		if err := json.Unmarshal(b, &schema); err != nil {
+			t.Fatalf("invalid schema %s: %v", name, err)
+		}
+		schemas[name] = schema
+	}
+
+	var walk func(path string, typ reflect.Type, node map[string]any)
+	walk = func(path string, typ reflect.Type, node map[string]any) {
+		if ref, ok := node["$ref"].(string); ok && schemas[ref] != nil {
+			node = schemas[ref]
+		}
+
+		switch typ.Kind() {
+		case reflect.Slice:
+			items, _ := node["items"].(map[string]any)
+			walk(path+"[]", typ.Elem(), items)
+			return
+		case reflect.Struct:
+		default:
+			return
+		}
+
+		props, _ := node["properties"].(map[string]any)
+		keys := map[string]bool{}
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if key == "" || key == "-" {
+				continue
+			}
+			keys[key] = true
+
+			child, ok := props[key].(map[string]any)
+			if !ok {
+				t.Fatalf("schema lacks %s.%s", path, key)
+			}
+			if f.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(path+"."+key, f.Type, child)
+			}
+		}
+		for key := range props {
+			if !keys[key] {
+				t.Fatalf("schema has %s.%s, which %s does not", path, key, typ.Name())
+			}
+		}
+	}
+
+	walk("", reflect.TypeOf(Config{}), schemas["config.schema.json"])
+	walk("", reflect.TypeOf(Server{}), schemas["server.schema.json"])
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls the file at path every interval and calls onChange whenever
+// its content changes, until ctx is cancelled. Polling needs no platform
+// support and follows the symlink swaps used for mounted config maps.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last, _ := os.ReadFile(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cur, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(cur, last) {
+				// a missing file is usually mid-replace; keep the old one
+				continue
+			}
+			last = cur
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package format
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+type avroCodec struct{}
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+var avroNull = json.RawMessage("null")
+
+func (avroCodec) schemaType() string { return SchemaTypeAvro }
+
+func (avroCodec) wireHeader() []byte { return nil }
+
+func avroType(kind fieldKind) any {
+	switch kind {
+	case kindLong:
+		return "long"
+	case kindDouble:
+		return "double"
+	case kindBytes:
+		return "bytes"
+	case kindTimestamp:
+		return map[string]string{"type": "long", "logicalType": "timestamp-millis"}
+	default:
+		return "string"
+	}
+}
+
+func avroRowRecord(name, namespace string, fields []schemaField) avroRecord {
+	rec := avroRecord{Type: "record", Name: name, Namespace: namespace, Fields: []avroField{}}
+	for _, f := range fields {
+		rec.Fields = append(rec.Fields, avroField{
+			Name:    f.name,
+			Type:    []any{"null", avroType(f.kind)},
+			Default: avroNull,
+		})
+	}
+	return rec
+}
+
+func (avroCodec) keySchema(namespace string, fields []schemaField, _ []int) string {
+	b, _ := json.Marshal(avroRowRecord("Key", namespace, fields))
+	return string(b)
+}
+
+func (avroCodec) valueSchema(namespace string, fields []schemaField, _ []int) string {
+	env := avroRecord{
+		Type:      "record",
+		Name:      "Envelope",
+		Namespace: namespace,
+		Fields: []avroField{
+			{Name: "before", Type: []any{"null", avroRowRecord("Value", "", fields)}, Default: avroNull},
+			{Name: "after", Type: []any{"null", "Value"}, Default: avroNull},
+			{Name: "op", Type: "string"},
+			{Name: "tx_id", Type: "string"},
+			{Name: "offset", Type: "string"},
+			{Name: "ts_ms", Type: "long"},
+		},
+	}
+	b, _ := json.Marshal(env)
+	return string(b)
+}
+
+func (avroCodec) encodeKey(fields []schemaField, pk map[string]any) []byte {
+	return avroAppendRow(nil, fields, pk)
+}
+
+func (avroCodec) encodeValue(fields []schemaField, env envelope) []byte {
+	var out []byte
+	for _, row := range []map[string]any{env.before, env.after} {
+		if row == nil {
+			out = avroAppendLong(out, 0)
+			continue
+		}
+		out = avroAppendLong(out, 1)
+		out = avroAppendRow(out, fields, row)
+	}
+	out = avroAppendString(out, env.op)
+	out = avroAppendString(out, env.txID)
+	out = avroAppendString(out, env.offset)
+	return avroAppendLong(out, env.tsMs)
+}
+
+func avroAppendRow(out []byte, fields []schemaField, row map[string]any) []byte {
+	for _, f := range fields {
+		v, ok := convert(f.kind, row[f.column])
+		if !ok {
+			out = avroAppendLong(out, 0)
+			continue
+		}
+		out = avroAppendLong(out, 1)
+
+		switch x := v.(type) {
+		case int64:
+			out = avroAppendLong(out, x)
+		case float64:
+			out = binary.LittleEndian.AppendUint64(out, math.Float64bits(x))
+		case []byte:
+			out = avroAppendLong(out, int64(len(x)))
+			out = append(out, x...)
+		case string:
+			out = avroAppendString(out, x)
+		}
+	}
+	return out
+}
+
+func avroAppendLong(out []byte, v int64) []byte {
+	return binary.AppendUvarint(out, uint64((v<<1)^(v>>63)))
+}
+
+func avroAppendString(out []byte, s string) []byte {
+	out = avroAppendLong(out, int64(len(s)))
+	return append(out, s...)
+}
@@ -0,0 +1,276 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// CanalEncoder emits Alibaba Canal flat messages (canal.mq.flatMessage=true):
+// one message per row change batch, values rendered as strings.
+//
+// Canal only has transaction entries in its protobuf protocol, and flat
+// message consumers such as Flink's canal-json reject types they do not
+// know. With txMarkers set, each transaction is still wrapped in
+// TRANSACTIONBEGIN and TRANSACTIONEND messages named after those entries.
+type CanalEncoder struct {
+	txMarkers bool
+
+	seq atomic.Int64
+	now func() time.Time
+}
+
+const (
+	canalTxBegin = "TRANSACTIONBEGIN"
+	canalTxEnd   = "TRANSACTIONEND"
+)
+
+type canalMessage struct {
+	Data      []map[string]any  `json:"data"`
+	Database  string            `json:"database"`
+	Es        int64             `json:"es"`
+	Gtid      string            `json:"gtid,omitempty"`
+	ID        int64             `json:"id"`
+	IsDdl     bool              `json:"isDdl"`
+	MysqlType map[string]string `json:"mysqlType"`
+	Old       []map[string]any  `json:"old"`
+	PkNames   []string          `json:"pkNames"`
+	SQL       string            `json:"sql"`
+	SQLType   map[string]int    `json:"sqlType"`
+	Table     string            `json:"table"`
+	Ts        int64             `json:"ts"`
+	Type      string            `json:"type"`
+}
+
+func (c *CanalEncoder) Encode(evt model.Event) ([]Message, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	var msgs []canalMessage
+	switch e := evt.(type) {
+	case model.RowChangeEvent:
+		for _, change := range e.Changes() {
+			if change.Op == model.OpTruncate {
+				// Canal reports truncates as DDL
+				msgs = append(msgs, canalMessage{
+					Database: change.Schema,
+					Table:    change.Table,
+					IsDdl:    true,
+					SQL:      truncateSQL(change),
+					Type:     "TRUNCATE",
+				})
+				continue
+			}
+
+			// flat messages have no snapshot type, and consumers reject
+			// types they do not know
+			typ := change.Op
+			if typ == model.OpRead {
+				typ = model.OpInsert
+			}
+			m := canalMessage{
+				Database:  change.Schema,
+				Table:     change.Table,
+				Type:      string(typ),
+				MysqlType: map[string]string{},
+				SQLType:   map[string]int{},
+			}
+			for _, col := range change.Columns {
+				m.MysqlType[col.Name] = col.Type
+				if col.SQLType != "" {
+					m.MysqlType[col.Name] = col.SQLType
+				}
+				m.SQLType[col.Name] = jdbcType(col.Type)
+			}
+
+			for _, row := range change.Rows {
+				if m.PkNames == nil {
+					m.PkNames = pkNames(change.Columns, row.PK)
+				}
+
+				image := row.After
+				if change.Op == model.OpDelete {
+					image = row.Before
+				}
+				m.Data = append(m.Data, canalRow(image, change.Columns))
+
+				if change.Op == model.OpUpdate {
+					m.Old = append(m.Old, canalRow(changedColumns(row.Before, row.After), change.Columns))
+				}
+			}
+			msgs = append(msgs, m)
+		}
+		if c.txMarkers {
+			msgs = c.wrapTx(evt, msgs)
+		}
+
+	case *model.BinlogDDLEvent:
+		kind, schema, table := ddlTarget(e.Query())
+		if schema == "" {
+			schema = e.Schema()
+		}
+		msgs = append(msgs, canalMessage{
+			Database: schema,
+			Table:    table,
+			IsDdl:    true,
+			SQL:      e.Query(),
+			Type:     canalDDLType(kind),
+		})
+
+	default:
+		return nil, nil
+	}
+
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		m.ID = c.seq.Add(1)
+		m.Es = evt.Timestamp().UnixMilli()
+		m.Ts = now().UnixMilli()
+
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Message{Value: b})
+	}
+	return out, nil
+}
+
+// wrapTx adds the transaction markers around msgs, the rows of evt. A
+// fragmented transaction opens with its first fragment and closes with its
+// last.
+func (c *CanalEncoder) wrapTx(evt model.Event, msgs []canalMessage) []canalMessage {
+	tx, ok := evt.(*model.TransactionEvent)
+	if !ok {
+		return msgs
+	}
+	frag := fragmentOf(tx)
+	gtid := tx.Meta().GTID
+
+	if frag.Index == 0 {
+		msgs = append([]canalMessage{{Type: canalTxBegin, Gtid: gtid}}, msgs...)
+	}
+	if frag.Last {
+		msgs = append(msgs, canalMessage{Type: canalTxEnd, Gtid: gtid})
+	}
+	return msgs
+}
+
+func truncateSQL(change model.RowChange) string {
+	if change.Statement != "" {
+		return change.Statement
+	}
+	return fmt.Sprintf("TRUNCATE TABLE `%s`.`%s`", change.Schema, change.Table)
+}
+
+func canalRow(row map[string]any, cols []model.Column) map[string]any {
+	if row == nil {
+		return nil
+	}
+	fsp := make(map[string]int, len(cols))
+	for _, col := range cols {
+		fsp[col.Name] = fractionalDigits(col)
+	}
+
+	out := make(map[string]any, len(row))
+	for k, v := range row {
+		switch x := v.(type) {
+		case nil:
+			out[k] = nil
+		case []byte:
+			out[k] = string(x)
+		case time.Time:
+			layout := "2006-01-02 15:04:05"
+			if n := fsp[k]; n > 0 {
+				layout += "." + strings.Repeat("0", n)
+			}
+			out[k] = x.Format(layout)
+		default:
+			out[k] = fmt.Sprint(x)
+		}
+	}
+	return out
+}
+
+// fractionalDigits returns the fractional seconds precision of a temporal
+// column, as in DATETIME(6), or 0 when its full type is unknown.
+func fractionalDigits(col model.Column) int {
+	switch col.Type {
+	case "datetime", "timestamp", "time":
+	default:
+		return 0
+	}
+	open := strings.IndexByte(col.SQLType, '(')
+	end := strings.IndexByte(col.SQLType, ')')
+	if open < 0 || end < open {
+		return 0
+	}
+	n, err := strconv.Atoi(col.SQLType[open+1 : end])
+	if err != nil || n < 0 || n > 6 {
+		return 0
+	}
+	return n
+}
+
+func canalDDLType(kind string) string {
+	switch kind {
+	case "CREATE TABLE":
+		return "CREATE"
+	case "ALTER TABLE":
+		return "ALTER"
+	case "DROP TABLE":
+		return "ERASE"
+	case "RENAME TABLE":
+		return "RENAME"
+	case "TRUNCATE TABLE":
+		return "TRUNCATE"
+	case "CREATE INDEX":
+		return "CINDEX"
+	case "DROP INDEX":
+		return "DINDEX"
+	default:
+		return "QUERY"
+	}
+}
+
+// jdbcType maps a column type to its java.sql.Types code, as Canal reports
+// in sqlType.
+func jdbcType(typ string) int {
+	switch typ {
+	case "bit":
+		return -7
+	case "tinyint":
+		return -6
+	case "smallint":
+		return 5
+	case "mediumint", "int":
+		return 4
+	case "bigint":
+		return -5
+	case "float":
+		return 7
+	case "double":
+		return 8
+	case "decimal":
+		return 3
+	case "char", "enum", "set":
+		return 1
+	case "date", "year":
+		return 91
+	case "time":
+		return 92
+	case "datetime", "timestamp":
+		return 93
+	case "blob", "geometry":
+		return 2004
+	default:
+		return 12
+	}
+}
@@ -0,0 +1,194 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func updateTx() *model.TransactionEvent {
+	return model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 300}, time.Unix(1700000000, 0), "3e11fa47-71ca-11e1-9e33-c80aa9429562:42", []model.RowChange{{
+		Schema:  "mydb",
+		Table:   "users",
+		Op:      model.OpUpdate,
+		Columns: []model.Column{{Name: "id", Type: "bigint"}, {Name: "name", Type: "varchar"}},
+		Rows: []model.RowData{
+			{
+				PK:     map[string]any{"id": int64(1)},
+				Before: map[string]any{"id": int64(1), "name": "old"},
+				After:  map[string]any{"id": int64(1), "name": "new"},
+			},
+			{
+				PK:     map[string]any{"id": int64(2)},
+				Before: map[string]any{"id": int64(2), "name": "a"},
+				After:  map[string]any{"id": int64(2), "name": "b"},
+			},
+		},
+	}}, model.TxMeta{XID: 42})
+}
+
+func TestCanalEncoder_Update(t *testing.T) {
+	enc := &CanalEncoder{}
+
+	msgs, err := enc.Encode(updateTx())
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+
+	var m canalMessage
+	if err := json.Unmarshal(msgs[0].Value, &m); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if m.Type != "UPDATE" || m.IsDdl || m.Database != "mydb" || m.Table != "users" {
+		t.Fatalf("unexpected header fields: %+v", m)
+	}
+	if len(m.Data) != 2 || m.Data[0]["id"] != "1" || m.Data[0]["name"] != "new" {
+		t.Fatalf("unexpected data: %v", m.Data)
+	}
+	if len(m.Old) != 2 || m.Old[0]["name"] != "old" {
+		t.Fatalf("unexpected old: %v", m.Old)
+	}
+	if _, ok := m.Old[0]["id"]; ok {
+		t.Fatal("unchanged columns must not appear in old")
+	}
+	if len(m.PkNames) != 1 || m.PkNames[0] != "id" {
+		t.Fatalf("unexpected pkNames: %v", m.PkNames)
+	}
+	if m.MysqlType["id"] != "bigint" || m.SQLType["id"] != -5 || m.SQLType["name"] != 12 {
+		t.Fatalf("unexpected types: %v %v", m.MysqlType, m.SQLType)
+	}
+}
+
+func TestCanalEncoder_SQLTypes(t *testing.T) {
+	tx := updateTx()
+	tx.Changes()[0].Columns = []model.Column{
+		{Name: "id", Type: "bigint", SQLType: "bigint(20) unsigned"},
+		{Name: "name", Type: "varchar", SQLType: "varchar(255)"},
+	}
+
+	msgs, err := (&CanalEncoder{}).Encode(tx)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+	var m canalMessage
+	_ = json.Unmarshal(msgs[0].Value, &m)
+	if m.MysqlType["id"] != "bigint(20) unsigned" || m.MysqlType["name"] != "varchar(255)" {
+		t.Fatalf("unexpected mysqlType: %v", m.MysqlType)
+	}
+	if m.SQLType["id"] != -5 || m.SQLType["name"] != 12 {
+		t.Fatalf("unexpected sqlType: %v", m.SQLType)
+	}
+}
+
+func TestCanalEncoder_FractionalSeconds(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	tx := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 300}, time.Unix(1700000000, 0), "tx-1", []model.RowChange{{
+		Schema: "mydb",
+		Table:  "events",
+		Op:     model.OpInsert,
+		Columns: []model.Column{
+			{Name: "at6", Type: "datetime", SQLType: "datetime(6)"},
+			{Name: "at3", Type: "timestamp", SQLType: "timestamp(3)"},
+			{Name: "at0", Type: "datetime", SQLType: "datetime"},
+		},
+		Rows: []model.RowData{{After: map[string]any{"at6": at, "at3": at, "at0": at}}},
+	}}, model.TxMeta{})
+
+	msgs, err := (&CanalEncoder{}).Encode(tx)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+	var m canalMessage
+	_ = json.Unmarshal(msgs[0].Value, &m)
+	want := map[string]any{"at6": "2024-01-02 03:04:05.123456", "at3": "2024-01-02 03:04:05.123", "at0": "2024-01-02 03:04:05"}
+	if !reflect.DeepEqual(m.Data[0], want) {
+		t.Fatalf("expected %v, got %v", want, m.Data[0])
+	}
+}
+
+func TestCanalEncoder_TxMarkers(t *testing.T) {
+	tx := func(frag model.Fragment) *model.TransactionEvent {
+		u := updateTx()
+		return model.NewTransactionFragment(u.Source(), u.Offset(), u.Timestamp(), u.TxID(), u.Changes(), model.TxMeta{GTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:42"}, frag)
+	}
+	types := func(enc *CanalEncoder, evt model.Event) []string {
+		msgs, err := enc.Encode(evt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, msg := range msgs {
+			var m canalMessage
+			_ = json.Unmarshal(msg.Value, &m)
+			out = append(out, m.Type)
+		}
+		return out
+	}
+
+	if got := types(&CanalEncoder{}, updateTx()); !reflect.DeepEqual(got, []string{"UPDATE"}) {
+		t.Fatalf("markers emitted without txMarkers: %v", got)
+	}
+
+	enc := &CanalEncoder{txMarkers: true}
+	tests := []struct {
+		name string
+		evt  model.Event
+		want []string
+	}{
+		{"whole", updateTx(), []string{"TRANSACTIONBEGIN", "UPDATE", "TRANSACTIONEND"}},
+		{"first fragment", tx(model.Fragment{Index: 0}), []string{"TRANSACTIONBEGIN", "UPDATE"}},
+		{"middle fragment", tx(model.Fragment{Index: 1, RowOffset: 2}), []string{"UPDATE"}},
+		{"last fragment", tx(model.Fragment{Index: 2, RowOffset: 4, Last: true}), []string{"UPDATE", "TRANSACTIONEND"}},
+	}
+	for _, tt := range tests {
+		if got := types(enc, tt.evt); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	msgs, _ := enc.Encode(tx(model.Fragment{Last: true}))
+	var end canalMessage
+	_ = json.Unmarshal(msgs[len(msgs)-1].Value, &end)
+	if end.Gtid != "3e11fa47-71ca-11e1-9e33-c80aa9429562:42" || end.ID == 0 {
+		t.Fatalf("unexpected end marker: %s", msgs[len(msgs)-1].Value)
+	}
+}
+
+func TestCanalEncoder_DDL(t *testing.T) {
+	enc := &CanalEncoder{}
+	ddl := model.NewBinlogDDLEvent(model.SourceMySQLBinlog, model.MySQLOffset{}, time.Now(), "", "mydb", "ALTER TABLE `orders` ADD COLUMN note VARCHAR(255)")
+
+	msgs, err := enc.Encode(ddl)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+
+	var m canalMessage
+	_ = json.Unmarshal(msgs[0].Value, &m)
+	if !m.IsDdl || m.Type != "ALTER" || m.Table != "orders" || m.Database != "mydb" {
+		t.Fatalf("unexpected ddl message: %s", msgs[0].Value)
+	}
+}
+
+func TestDDLTarget(t *testing.T) {
+	cases := []struct {
+		query, kind, schema, table string
+	}{
+		{"CREATE TABLE IF NOT EXISTS orders (id INT)", "CREATE TABLE", "", "orders"},
+		{"ALTER TABLE `shop`.`orders` ADD c INT", "ALTER TABLE", "shop", "orders"},
+		{"DROP TABLE IF EXISTS temp_table_for_cdc_test /* generated by server */", "DROP TABLE", "", "temp_table_for_cdc_test"},
+		{"TRUNCATE users", "TRUNCATE TABLE", "", "users"},
+		{"CREATE UNIQUE INDEX idx ON users (email)", "CREATE INDEX", "", "users"},
+		{"DROP INDEX idx ON users", "DROP INDEX", "", "users"},
+	}
+
+	for _, tc := range cases {
+		kind, schema, table := ddlTarget(tc.query)
+		if kind != tc.kind || schema != tc.schema || table != tc.table {
+			t.Fatalf("ddlTarget(%q) = %q %q %q", tc.query, kind, schema, table)
+		}
+	}
+}
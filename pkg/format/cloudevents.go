@@ -0,0 +1,127 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// CloudEventsEncoder emits one CloudEvents 1.0 event per row. In structured
+// mode the whole event is the JSON body; in binary mode the attributes are
+// returned as ce-* headers and the body holds only the data.
+type CloudEventsEncoder struct {
+	serverName string
+	binary     bool
+}
+
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject"`
+	Time            string         `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	TxID            string         `json:"txid,omitempty"`
+	Data            cloudEventData `json:"data"`
+}
+
+type cloudEventData struct {
+	PK     map[string]any `json:"pk,omitempty"`
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+func NewCloudEventsEncoder(serverName, mode string) (*CloudEventsEncoder, error) {
+	switch mode {
+	case "", CloudEventsStructured:
+		return &CloudEventsEncoder{serverName: serverName}, nil
+	case CloudEventsBinary:
+		return &CloudEventsEncoder{serverName: serverName, binary: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cloudevents mode: %s", mode)
+	}
+}
+
+func (c *CloudEventsEncoder) Encode(evt model.Event) ([]Message, error) {
+	e, ok := evt.(model.RowChangeEvent)
+	if !ok {
+		return nil, nil
+	}
+
+	// the transaction ID and row index are stable across replays, so
+	// consumers can deduplicate on id. The offset is not: all but the last
+	// fragment of a transaction carry the previous commit's.
+	base := e.TxID()
+	if base == "" {
+		base = evt.Offset().String()
+	}
+
+	var msgs []Message
+	idx := fragmentOf(evt).RowOffset
+	for _, change := range e.Changes() {
+		for _, row := range changeRows(change) {
+			ce := cloudEvent{
+				SpecVersion:     "1.0",
+				ID:              fmt.Sprintf("%s#%d", base, idx),
+				Source:          "/" + string(evt.Source()) + "/" + c.serverName,
+				Type:            "io.tabellarius.row." + strings.ToLower(string(change.Op)),
+				Subject:         change.Schema + "." + change.Table,
+				Time:            evt.Timestamp().UTC().Format(time.RFC3339Nano),
+				DataContentType: "application/json",
+				TxID:            e.TxID(),
+				Data:            cloudEventData{PK: row.PK, Before: row.Before, After: row.After},
+			}
+			idx++
+
+			msg, err := c.message(ce)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (c *CloudEventsEncoder) message(ce cloudEvent) (Message, error) {
+	if !c.binary {
+		b, err := json.Marshal(ce)
+		if err != nil {
+			return Message{}, err
+		}
+		return Message{
+			Value:   b,
+			Headers: map[string]string{"content-type": "application/cloudevents+json; charset=UTF-8"},
+		}, nil
+	}
+
+	b, err := json.Marshal(ce.Data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := map[string]string{
+		"ce-specversion": ce.SpecVersion,
+		"ce-id":          ce.ID,
+		"ce-source":      ce.Source,
+		"ce-type":        ce.Type,
+		"ce-subject":     ce.Subject,
+		"ce-time":        ce.Time,
+		"content-type":   ce.DataContentType,
+	}
+	if ce.TxID != "" {
+		headers["ce-txid"] = ce.TxID
+	}
+
+	return Message{Value: b, Headers: headers}, nil
+}
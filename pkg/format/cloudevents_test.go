@@ -0,0 +1,81 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func cloudEventsTx() *model.TransactionEvent {
+	return model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000002", Pos: 99}, time.Unix(1700000000, 0), "tx-9", []model.RowChange{{
+		Schema: "mydb",
+		Table:  "orders",
+		Op:     model.OpUpdate,
+		Rows: []model.RowData{
+			{PK: map[string]any{"id": 1}, Before: map[string]any{"id": 1, "status": "pending"}, After: map[string]any{"id": 1, "status": "done"}},
+			{PK: map[string]any{"id": 2}, Before: map[string]any{"id": 2, "status": "pending"}, After: map[string]any{"id": 2, "status": "done"}},
+		},
+	}}, model.TxMeta{})
+}
+
+func TestCloudEventsEncoder_Structured(t *testing.T) {
+	enc, _ := NewCloudEventsEncoder("dbserver1", CloudEventsStructured)
+
+	msgs, err := enc.Encode(cloudEventsTx())
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(msgs))
+	}
+
+	var ce map[string]any
+	if err := json.Unmarshal(msgs[1].Value, &ce); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if ce["specversion"] != "1.0" || ce["id"] != "tx-9#1" {
+		t.Fatalf("unexpected attributes: %v", ce)
+	}
+	if ce["type"] != "io.tabellarius.row.update" || ce["subject"] != "mydb.orders" || ce["source"] != "/mysql-binlog/dbserver1" {
+		t.Fatalf("unexpected routing attributes: %v", ce)
+	}
+	data := ce["data"].(map[string]any)
+	if data["after"].(map[string]any)["status"] != "done" {
+		t.Fatalf("unexpected data: %v", data)
+	}
+
+	again, _ := enc.Encode(cloudEventsTx())
+	if string(again[1].Value) != string(msgs[1].Value) {
+		t.Fatal("event id must be deterministic across replays")
+	}
+}
+
+func TestCloudEventsEncoder_Binary(t *testing.T) {
+	enc, _ := NewCloudEventsEncoder("dbserver1", CloudEventsBinary)
+
+	msgs, err := enc.Encode(cloudEventsTx())
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	h := msgs[0].Headers
+	if h["ce-id"] != "tx-9#0" || h["ce-specversion"] != "1.0" || h["ce-txid"] != "tx-9" {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(msgs[0].Value, &data); err != nil {
+		t.Fatalf("invalid data: %v", err)
+	}
+	if _, ok := data["before"]; !ok {
+		t.Fatalf("binary body should carry only data: %s", msgs[0].Value)
+	}
+}
+
+func TestNewCloudEventsEncoder_InvalidMode(t *testing.T) {
+	if _, err := NewCloudEventsEncoder("x", "batched"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,79 @@
+package format
+
+import (
+	"strings"
+)
+
+// ddlTarget extracts the statement kind and target of a DDL query, e.g.
+// ("ALTER TABLE", "mydb", "orders"). schema is empty when the statement does
+// not qualify the name.
+func ddlTarget(query string) (kind, schema, name string) {
+	words := strings.Fields(stripComments(query))
+	upper := make([]string, len(words))
+	for i, w := range words {
+		upper[i] = strings.ToUpper(w)
+	}
+
+	at := func(i int) string {
+		if i < len(upper) {
+			return upper[i]
+		}
+		return ""
+	}
+
+	if len(words) < 2 {
+		return strings.Join(upper, " "), "", ""
+	}
+
+	i := 2
+	switch {
+	case at(0) == "CREATE" && (at(1) == "INDEX" || at(1) == "UNIQUE" || at(1) == "FULLTEXT" || at(1) == "SPATIAL"),
+		at(0) == "DROP" && at(1) == "INDEX":
+		for i < len(upper) && upper[i] != "ON" {
+			i++
+		}
+		kind = at(0) + " INDEX"
+		i++
+	case at(0) == "TRUNCATE":
+		kind = "TRUNCATE TABLE"
+		if at(1) != "TABLE" {
+			i = 1
+		}
+	default:
+		kind = at(0) + " " + at(1)
+		if at(1) == "TEMPORARY" {
+			kind = at(0) + " " + at(2)
+			i = 3
+		}
+	}
+
+	for at(i) == "IF" || at(i) == "NOT" || at(i) == "EXISTS" {
+		i++
+	}
+	if i >= len(words) {
+		return kind, "", ""
+	}
+
+	target := strings.Trim(words[i], "`;(")
+	if dot := strings.Index(target, "`.`"); dot >= 0 {
+		return kind, target[:dot], target[dot+3:]
+	}
+	if dot := strings.Index(target, "."); dot >= 0 {
+		return kind, strings.Trim(target[:dot], "`"), strings.Trim(target[dot+1:], "`")
+	}
+	return kind, "", target
+}
+
+func stripComments(q string) string {
+	for {
+		start := strings.Index(q, "/*")
+		if start < 0 {
+			return q
+		}
+		end := strings.Index(q[start:], "*/")
+		if end < 0 {
+			return q[:start]
+		}
+		q = q[:start] + " " + q[start+end+2:]
+	}
+}
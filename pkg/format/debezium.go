@@ -0,0 +1,214 @@
+package format
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	cdc "github.com/cursus-io/tabellarius"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// DebeziumEncoder emits Debezium MySQL connector style key/value pairs
+// (JSON converter, schemas disabled). Each deleted row is followed by a
+// tombstone so compacted topics can drop the key.
+type DebeziumEncoder struct {
+	serverName string
+	now        func() time.Time
+}
+
+type debeziumSource struct {
+	Version   string  `json:"version"`
+	Connector string  `json:"connector"`
+	Name      string  `json:"name"`
+	TsMs      int64   `json:"ts_ms"`
+	Snapshot  string  `json:"snapshot"`
+	DB        string  `json:"db"`
+	Table     string  `json:"table"`
+	ServerID  uint32  `json:"server_id"`
+	GTID      *string `json:"gtid"`
+	File      string  `json:"file"`
+	Pos       uint32  `json:"pos"`
+	Row       int     `json:"row"`
+	Thread    *int64  `json:"thread"`
+	Query     *string `json:"query"`
+}
+
+type debeziumTransaction struct {
+	ID                  string `json:"id"`
+	TotalOrder          int    `json:"total_order"`
+	DataCollectionOrder int    `json:"data_collection_order"`
+}
+
+type debeziumValue struct {
+	Before      map[string]any       `json:"before"`
+	After       map[string]any       `json:"after"`
+	Source      debeziumSource       `json:"source"`
+	Op          string               `json:"op"`
+	TsMs        int64                `json:"ts_ms"`
+	Transaction *debeziumTransaction `json:"transaction"`
+}
+
+func (d *DebeziumEncoder) Encode(evt model.Event) ([]Message, error) {
+	e, ok := evt.(model.RowChangeEvent)
+	if !ok {
+		// schema changes and boundaries go to separate topics in Debezium;
+		// table consumers never see them
+		return nil, nil
+	}
+
+	now := time.Now
+	if d.now != nil {
+		now = d.now
+	}
+
+	meta := txMeta(evt)
+	var gtid *string
+	if meta.GTID != "" {
+		gtid = &meta.GTID
+	}
+
+	var file string
+	var pos uint32
+	if off, ok := evt.Offset().(model.MySQLOffset); ok {
+		file, pos = off.File, off.Pos
+	}
+
+	var msgs []Message
+	frag := fragmentOf(evt)
+	totalOrder := frag.RowOffset
+	perTable := map[string]int{}
+	changes := e.Changes()
+
+	for ci, change := range changes {
+		rows := changeRows(change)
+		for ri, row := range rows {
+			totalOrder++
+			perTable[change.Schema+"."+change.Table]++
+
+			// a snapshot is published as one transaction, so its final row
+			// is the last one of the last fragment
+			snapshot := "false"
+			if change.Op == model.OpRead {
+				snapshot = "true"
+				if frag.Last && ci == len(changes)-1 && ri == len(rows)-1 {
+					snapshot = "last"
+				}
+			}
+
+			v := debeziumValue{
+				Before: debeziumRow(change.Columns, row.Before),
+				After:  debeziumRow(change.Columns, row.After),
+				Source: debeziumSource{
+					Version:   cdc.Version,
+					Connector: "mysql",
+					Name:      d.serverName,
+					TsMs:      evt.Timestamp().UnixMilli(),
+					Snapshot:  snapshot,
+					DB:        change.Schema,
+					Table:     change.Table,
+					ServerID:  meta.ServerID,
+					GTID:      gtid,
+					File:      file,
+					Pos:       pos,
+					Row:       ri,
+				},
+				Op:   debeziumOp(change.Op),
+				TsMs: now().UnixMilli(),
+				Transaction: &debeziumTransaction{
+					ID:                  e.TxID(),
+					TotalOrder:          totalOrder,
+					DataCollectionOrder: perTable[change.Schema+"."+change.Table],
+				},
+			}
+
+			var key []byte
+			if len(row.PK) > 0 {
+				b, err := json.Marshal(debeziumRow(change.Columns, row.PK))
+				if err != nil {
+					return nil, err
+				}
+				key = b
+			}
+
+			value, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, Message{Key: key, Value: value})
+
+			if change.Op == model.OpDelete && key != nil {
+				msgs = append(msgs, Message{Key: key})
+			}
+		}
+	}
+
+	return msgs, nil
+}
+
+func debeziumOp(op model.OpType) string {
+	switch op {
+	case model.OpInsert:
+		return "c"
+	case model.OpUpdate:
+		return "u"
+	case model.OpDelete:
+		return "d"
+	case model.OpTruncate:
+		return "t"
+	case model.OpRead:
+		return "r"
+	default:
+		return strings.ToLower(string(op))
+	}
+}
+
+// debeziumRow converts values to Debezium's default temporal representations:
+// DATETIME as epoch millis, TIMESTAMP as an ISO-8601 UTC string and DATE as
+// days since epoch.
+func debeziumRow(cols []model.Column, row map[string]any) map[string]any {
+	if row == nil {
+		return nil
+	}
+
+	types := make(map[string]string, len(cols))
+	for _, c := range cols {
+		types[c.Name] = c.Type
+	}
+
+	out := make(map[string]any, len(row))
+	for k, v := range row {
+		out[k] = debeziumValueOf(types[k], v)
+	}
+	return out
+}
+
+func debeziumValueOf(typ string, v any) any {
+	switch typ {
+	case "datetime":
+		if t, ok := v.(time.Time); ok {
+			return t.UnixMilli()
+		}
+	case "timestamp":
+		if t, ok := v.(time.Time); ok {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	case "date":
+		var t time.Time
+		switch x := v.(type) {
+		case time.Time:
+			t = x
+		case string:
+			p, err := time.Parse("2006-01-02", x)
+			if err != nil {
+				return v
+			}
+			t = p
+		default:
+			return v
+		}
+		return int(math.Floor(float64(t.Unix()) / 86400))
+	}
+	return v
+}
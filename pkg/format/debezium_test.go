@@ -0,0 +1,133 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func newTestTx(op model.OpType, row model.RowData) *model.TransactionEvent {
+	return model.NewTransactionEvent(
+		model.SourceMySQLBinlog,
+		model.MySQLOffset{File: "binlog.000003", Pos: 4567},
+		time.Unix(1700000000, 0),
+		"tx-1",
+		[]model.RowChange{{
+			Schema: "mydb",
+			Table:  "users",
+			Op:     op,
+			Columns: []model.Column{
+				{Name: "id", Type: "bigint"},
+				{Name: "born", Type: "date"},
+			},
+			Rows: []model.RowData{row},
+		}},
+		model.TxMeta{},
+	)
+}
+
+func TestDebeziumEncoder_Insert(t *testing.T) {
+	enc := &DebeziumEncoder{serverName: "dbserver1"}
+	evt := newTestTx(model.OpInsert, model.RowData{
+		PK:    map[string]any{"id": int64(1)},
+		After: map[string]any{"id": int64(1), "born": "1970-01-11"},
+	})
+
+	msgs, err := enc.Encode(evt)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	if string(msgs[0].Key) != `{"id":1}` {
+		t.Fatalf("unexpected key: %s", msgs[0].Key)
+	}
+
+	var v map[string]any
+	if err := json.Unmarshal(msgs[0].Value, &v); err != nil {
+		t.Fatalf("invalid value: %v", err)
+	}
+	if v["op"] != "c" || v["before"] != nil {
+		t.Fatalf("unexpected envelope: %s", msgs[0].Value)
+	}
+	after := v["after"].(map[string]any)
+	if after["born"] != float64(10) {
+		t.Fatalf("expected date as epoch days, got %v", after["born"])
+	}
+
+	src := v["source"].(map[string]any)
+	if src["file"] != "binlog.000003" || src["pos"] != float64(4567) || src["name"] != "dbserver1" {
+		t.Fatalf("unexpected source block: %v", src)
+	}
+	if src["ts_ms"] != float64(1700000000000) {
+		t.Fatalf("unexpected source ts_ms: %v", src["ts_ms"])
+	}
+}
+
+func TestDebeziumEncoder_DeleteTombstone(t *testing.T) {
+	enc := &DebeziumEncoder{serverName: "dbserver1"}
+	evt := newTestTx(model.OpDelete, model.RowData{
+		PK:     map[string]any{"id": int64(1)},
+		Before: map[string]any{"id": int64(1)},
+	})
+
+	msgs, err := enc.Encode(evt)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected delete + tombstone, got %d", len(msgs))
+	}
+	if msgs[1].Value != nil || string(msgs[1].Key) != `{"id":1}` {
+		t.Fatalf("unexpected tombstone: %+v", msgs[1])
+	}
+
+	var v map[string]any
+	_ = json.Unmarshal(msgs[0].Value, &v)
+	if v["op"] != "d" || v["after"] != nil {
+		t.Fatalf("unexpected delete envelope: %s", msgs[0].Value)
+	}
+}
+
+func TestDebeziumEncoder_SkipsDDL(t *testing.T) {
+	enc := &DebeziumEncoder{}
+	ddl := model.NewBinlogDDLEvent(model.SourceMySQLBinlog, model.MySQLOffset{}, time.Now(), "", "mydb", "ALTER TABLE users ADD c INT")
+
+	msgs, err := enc.Encode(ddl)
+	if err != nil || len(msgs) != 0 {
+		t.Fatalf("expected no messages for DDL, got %d (%v)", len(msgs), err)
+	}
+}
+
+func TestDebeziumEncoder_SourceOrigin(t *testing.T) {
+	enc := &DebeziumEncoder{serverName: "dbserver1"}
+	evt := model.NewTransactionEvent(
+		model.SourceMySQLBinlog,
+		model.MySQLOffset{File: "binlog.000003", Pos: 4567},
+		time.Unix(1700000000, 0),
+		"3e11fa47-71ca-11e1-9e33-c80aa9429562:7",
+		[]model.RowChange{{
+			Schema: "mydb",
+			Table:  "users",
+			Op:     model.OpInsert,
+			Rows:   []model.RowData{{PK: map[string]any{"id": int64(1)}, After: map[string]any{"id": int64(1)}}},
+		}},
+		model.TxMeta{GTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:7", ServerID: 223344},
+	)
+
+	msgs, err := enc.Encode(evt)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	var v debeziumValue
+	if err := json.Unmarshal(msgs[0].Value, &v); err != nil {
+		t.Fatalf("invalid value: %v", err)
+	}
+	if v.Source.GTID == nil || *v.Source.GTID != "3e11fa47-71ca-11e1-9e33-c80aa9429562:7" || v.Source.ServerID != 223344 {
+		t.Fatalf("unexpected source: %+v", v.Source)
+	}
+}
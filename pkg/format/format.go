@@ -0,0 +1,100 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+const (
+	JSON        = "json"
+	Debezium    = "debezium"
+	Avro        = "avro"
+	Protobuf    = "protobuf"
+	CloudEvents = "cloudevents"
+	Canal       = "canal-json"
+	Maxwell     = "maxwell"
+)
+
+// Message is one encoded record. A nil Value is a tombstone.
+type Message struct {
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// Encoder turns a source event into zero or more messages for a sink.
+type Encoder interface {
+	Encode(evt model.Event) ([]Message, error)
+}
+
+type Options struct {
+	// ServerName identifies this source in envelopes that carry one
+	// (Debezium's source.name).
+	ServerName string
+	// RegistryURL is the schema registry used by the avro and protobuf formats.
+	RegistryURL string
+	// CloudEventsMode is "structured" (default) or "binary".
+	CloudEventsMode string
+	// CanalTxMarkers wraps canal-json transactions in begin and end
+	// messages.
+	CanalTxMarkers bool
+}
+
+func New(name string, opts Options) (Encoder, error) {
+	if opts.ServerName == "" {
+		opts.ServerName = "tabellarius"
+	}
+
+	switch name {
+	case "", JSON:
+		return &JSONEncoder{}, nil
+	case Debezium:
+		return &DebeziumEncoder{serverName: opts.ServerName}, nil
+	case Avro, Protobuf:
+		if opts.RegistryURL == "" {
+			return nil, fmt.Errorf("format %s requires a schema registry url", name)
+		}
+		registry := NewRegistry(opts.RegistryURL)
+		if name == Avro {
+			return NewAvroEncoder(opts.ServerName, registry), nil
+		}
+		return NewProtobufEncoder(opts.ServerName, registry), nil
+	case CloudEvents:
+		return NewCloudEventsEncoder(opts.ServerName, opts.CloudEventsMode)
+	case Canal:
+		return &CanalEncoder{txMarkers: opts.CanalTxMarkers}, nil
+	case Maxwell:
+		return MaxwellEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", name)
+	}
+}
+
+// txMeta returns the transaction origin carried by evt, if any.
+func txMeta(evt model.Event) model.TxMeta {
+	if m, ok := evt.(interface{ Meta() model.TxMeta }); ok {
+		return m.Meta()
+	}
+	return model.TxMeta{}
+}
+
+// changeRows returns the rows to emit a message for. A truncate has no rows
+// but is still emitted once, as a message without row images.
+func changeRows(c model.RowChange) []model.RowData {
+	if c.Op == model.OpTruncate {
+		return []model.RowData{{}}
+	}
+	return c.Rows
+}
+
+// fragmentOf returns where evt sits in a fragmented transaction. An event
+// that is not a fragment is reported as the only, last one.
+func fragmentOf(evt model.Event) model.Fragment {
+	if tx, ok := evt.(*model.TransactionEvent); ok {
+		if f, ok := tx.Fragment(); ok {
+			return f
+		}
+	}
+	return model.Fragment{Last: true}
+}
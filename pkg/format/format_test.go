@@ -0,0 +1,126 @@
+package format
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", JSON, Debezium, CloudEvents, Canal, Maxwell} {
+		if _, err := New(name, Options{}); err != nil {
+			t.Fatalf("New(%q) failed: %v", name, err)
+		}
+	}
+
+	if _, err := New("xml", Options{}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if _, err := New(Avro, Options{}); err == nil {
+		t.Fatal("expected error for avro without registry")
+	}
+	if _, err := New(Protobuf, Options{RegistryURL: "http://localhost:8081"}); err != nil {
+		t.Fatalf("New(protobuf) failed: %v", err)
+	}
+}
+
+func TestJSONEncoder_Transaction(t *testing.T) {
+	evt := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 10}, time.Now(), "tx-1", []model.RowChange{
+		{Schema: "mydb", Table: "users", Op: model.OpInsert, Rows: []model.RowData{{After: map[string]any{"id": 1}}}},
+	}, model.TxMeta{})
+
+	msgs, err := JSONEncoder{}.Encode(evt)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %v %v", msgs, err)
+	}
+
+	var v map[string]any
+	if err := json.Unmarshal(msgs[0].Value, &v); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if v["type"] != "tx" || v["tx_id"] != "tx-1" || v["offset"] != "binlog.000001:10" {
+		t.Fatalf("unexpected payload: %s", msgs[0].Value)
+	}
+}
+
+func TestEncoders_Truncate(t *testing.T) {
+	evt := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 10}, time.Unix(1700000000, 0), "tx-1", []model.RowChange{
+		{Schema: "mydb", Table: "users", Op: model.OpTruncate, Columns: []model.Column{{Name: "id", Type: "bigint"}}},
+	}, model.TxMeta{})
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{JSON, []string{`"op":"TRUNCATE"`}},
+		{Debezium, []string{`"op":"t"`, `"before":null`, `"after":null`}},
+		{CloudEvents, []string{`"type":"io.tabellarius.row.truncate"`, `"subject":"mydb.users"`}},
+		{Canal, []string{`"type":"TRUNCATE"`, `"isDdl":true`, "TRUNCATE TABLE `mydb`.`users`"}},
+		{Maxwell, []string{`"type":"table-alter"`, "TRUNCATE TABLE `mydb`.`users`", `"database":"mydb"`}},
+	}
+
+	for _, tt := range tests {
+		enc, err := New(tt.format, Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		msgs, err := enc.Encode(evt)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("%s: expected one message, got %d (%v)", tt.format, len(msgs), err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(msgs[0].Value), want) {
+				t.Fatalf("%s: expected %s in %s", tt.format, want, msgs[0].Value)
+			}
+		}
+	}
+}
+
+func TestEncoders_SnapshotRead(t *testing.T) {
+	rows := []model.RowData{
+		{PK: map[string]any{"id": int64(1)}, After: map[string]any{"id": int64(1)}},
+		{PK: map[string]any{"id": int64(2)}, After: map[string]any{"id": int64(2)}},
+	}
+	evt := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 10}, time.Unix(1700000000, 0), "snapshot:binlog.000001:10", []model.RowChange{
+		{Schema: "mydb", Table: "users", Op: model.OpRead, Columns: []model.Column{{Name: "id", Type: "bigint"}}, Rows: rows},
+	}, model.TxMeta{})
+
+	tests := []struct {
+		format string
+		want   [][]string
+	}{
+		{Debezium, [][]string{{`"op":"r"`, `"snapshot":"true"`}, {`"op":"r"`, `"snapshot":"last"`}}},
+		{CloudEvents, [][]string{{`"type":"io.tabellarius.row.read"`}, {`"type":"io.tabellarius.row.read"`}}},
+		{Maxwell, [][]string{{`"type":"bootstrap-insert"`}, {`"type":"bootstrap-insert"`, `"commit":true`}}},
+		{Canal, [][]string{{`"type":"INSERT"`}}},
+		{JSON, [][]string{{`"op":"READ"`}}},
+	}
+
+	for _, tt := range tests {
+		enc, err := New(tt.format, Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		msgs, err := enc.Encode(evt)
+		if err != nil || len(msgs) != len(tt.want) {
+			t.Fatalf("%s: expected %d messages, got %d (%v)", tt.format, len(tt.want), len(msgs), err)
+		}
+		for i, wants := range tt.want {
+			for _, want := range wants {
+				if !strings.Contains(string(msgs[i].Value), want) {
+					t.Fatalf("%s: expected %s in %s", tt.format, want, msgs[i].Value)
+				}
+			}
+		}
+	}
+
+	// a fragment other than the last does not end the snapshot
+	frag := model.NewTransactionFragment(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 10}, time.Unix(1700000000, 0), "snapshot:binlog.000001:10", evt.Changes(), model.TxMeta{}, model.Fragment{Index: 0})
+	msgs, err := (&DebeziumEncoder{}).Encode(frag)
+	if err != nil || len(msgs) != 2 || strings.Contains(string(msgs[1].Value), `"snapshot":"last"`) {
+		t.Fatalf("unexpected fragment encoding: %v %v", msgs, err)
+	}
+}
@@ -0,0 +1,71 @@
+package format
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// JSONEncoder writes one message per event with the event's own fields.
+type JSONEncoder struct{}
+
+type jsonEvent struct {
+	Type      string            `json:"type"`
+	Source    model.SourceType  `json:"source"`
+	Offset    string            `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	TxID      string            `json:"tx_id,omitempty"`
+	Tx        *model.TxMeta     `json:"tx,omitempty"`
+	Fragment  *model.Fragment   `json:"fragment,omitempty"`
+	Changes   []model.RowChange `json:"changes,omitempty"`
+	Schema    string            `json:"schema,omitempty"`
+	Query     string            `json:"query,omitempty"`
+	Kind      string            `json:"kind,omitempty"`
+	Code      string            `json:"code,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+func (JSONEncoder) Encode(evt model.Event) ([]Message, error) {
+	out := jsonEvent{
+		Source:    evt.Source(),
+		Offset:    evt.Offset().String(),
+		Timestamp: evt.Timestamp(),
+	}
+
+	switch e := evt.(type) {
+	case model.RowChangeEvent:
+		out.Type = "tx"
+		out.TxID = e.TxID()
+		if meta := txMeta(evt); !meta.CommitTime.IsZero() {
+			out.Tx = &meta
+		}
+		if tx, ok := evt.(*model.TransactionEvent); ok {
+			if f, ok := tx.Fragment(); ok {
+				out.Fragment = &f
+			}
+		}
+		out.Changes = e.Changes()
+	case *model.BinlogDDLEvent:
+		out.Type = e.Type()
+		out.TxID = e.TxID()
+		out.Schema = e.Schema()
+		out.Query = e.Query()
+	case *model.TransactionBoundaryEvent:
+		out.Type = "boundary"
+		out.TxID = e.TxID()
+		out.Kind = string(e.Kind())
+	case *model.WarningEvent:
+		out.Type = "warning"
+		out.Code = e.Code()
+		out.Message = e.Message()
+	default:
+		out.Type = "unknown"
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	return []Message{{Value: b}}, nil
+}
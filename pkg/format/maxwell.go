@@ -0,0 +1,197 @@
+package format
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// MaxwellEncoder emits Maxwell's daemon JSON: one message per row with xid,
+// xoffset and commit=true on the last row of the transaction (the last
+// fragment's, for fragmented transactions), and
+// table-create/table-alter/... messages for DDL. Maxwell has no truncate row
+// type, so truncates are published like the TRUNCATE TABLE DDL.
+type MaxwellEncoder struct{}
+
+type maxwellRow struct {
+	Database string         `json:"database"`
+	Table    string         `json:"table"`
+	Type     string         `json:"type"`
+	Ts       int64          `json:"ts"`
+	Xid      uint64         `json:"xid"`
+	Xoffset  int            `json:"xoffset"`
+	Commit   bool           `json:"commit,omitempty"`
+	Position string         `json:"position"`
+	Data     map[string]any `json:"data"`
+	Old      map[string]any `json:"old,omitempty"`
+}
+
+type maxwellDDL struct {
+	Type     string `json:"type"`
+	Database string `json:"database"`
+	Table    string `json:"table,omitempty"`
+	SQL      string `json:"sql"`
+	Ts       int64  `json:"ts"`
+	Position string `json:"position"`
+}
+
+func (MaxwellEncoder) Encode(evt model.Event) ([]Message, error) {
+	switch e := evt.(type) {
+	case model.RowChangeEvent:
+		return maxwellRows(e)
+
+	case *model.BinlogDDLEvent:
+		kind, schema, table := ddlTarget(e.Query())
+		if schema == "" {
+			schema = e.Schema()
+		}
+		b, err := json.Marshal(maxwellDDL{
+			Type:     maxwellDDLType(kind),
+			Database: schema,
+			Table:    table,
+			SQL:      e.Query(),
+			Ts:       e.Timestamp().Unix(),
+			Position: e.Offset().String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []Message{{Value: b}}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func maxwellRows(e model.RowChangeEvent) ([]Message, error) {
+	total := 0
+	for _, change := range e.Changes() {
+		total += len(change.Rows)
+	}
+
+	xid := maxwellXid(txMeta(e), e.TxID())
+	frag := fragmentOf(e)
+
+	var msgs []Message
+	xoffset := frag.RowOffset
+	total += frag.RowOffset
+	for _, change := range e.Changes() {
+		if change.Op == model.OpTruncate {
+			value, err := json.Marshal(maxwellDDL{
+				Type:     maxwellDDLType("TRUNCATE TABLE"),
+				Database: change.Schema,
+				Table:    change.Table,
+				SQL:      truncateSQL(change),
+				Ts:       e.Timestamp().Unix(),
+				Position: e.Offset().String(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, Message{Value: value})
+			continue
+		}
+
+		for _, row := range change.Rows {
+			r := maxwellRow{
+				Database: change.Schema,
+				Table:    change.Table,
+				Type:     maxwellType(change.Op),
+				Ts:       e.Timestamp().Unix(),
+				Xid:      xid,
+				Xoffset:  xoffset,
+				Commit:   frag.Last && xoffset == total-1,
+				Position: e.Offset().String(),
+				Data:     maxwellData(row.After),
+			}
+			switch change.Op {
+			case model.OpDelete:
+				r.Data = maxwellData(row.Before)
+			case model.OpUpdate:
+				r.Old = maxwellData(changedColumns(row.Before, row.After))
+			}
+			xoffset++
+
+			value, err := json.Marshal(r)
+			if err != nil {
+				return nil, err
+			}
+			key, err := maxwellKey(change, row.PK)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, Message{Key: key, Value: value})
+		}
+	}
+	return msgs, nil
+}
+
+// maxwellType returns the row type; snapshot rows are typed like the rows of
+// a Maxwell bootstrap.
+func maxwellType(op model.OpType) string {
+	if op == model.OpRead {
+		return "bootstrap-insert"
+	}
+	return strings.ToLower(string(op))
+}
+
+// maxwellKey follows Maxwell's hash key format: {"database":..,"table":..,"pk.<col>":..}.
+func maxwellKey(change model.RowChange, pk map[string]any) ([]byte, error) {
+	key := map[string]any{"database": change.Schema, "table": change.Table}
+	for k, v := range maxwellData(pk) {
+		key["pk."+k] = v
+	}
+	return json.Marshal(key)
+}
+
+func maxwellData(row map[string]any) map[string]any {
+	if row == nil {
+		return nil
+	}
+	out := make(map[string]any, len(row))
+	for k, v := range row {
+		switch x := v.(type) {
+		case []byte:
+			out[k] = string(x)
+		case time.Time:
+			out[k] = x.Format("2006-01-02 15:04:05")
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// maxwellXid returns the storage engine XID when the transaction has one and
+// a stable hash of the transaction ID otherwise, so rows of one transaction
+// share an xid.
+func maxwellXid(meta model.TxMeta, txID string) uint64 {
+	if meta.XID != 0 {
+		return meta.XID
+	}
+	h := fnv.New64a()
+	h.Write([]byte(txID))
+	return h.Sum64()
+}
+
+func maxwellDDLType(kind string) string {
+	switch kind {
+	case "CREATE TABLE":
+		return "table-create"
+	case "ALTER TABLE", "RENAME TABLE", "CREATE INDEX", "DROP INDEX", "TRUNCATE TABLE":
+		return "table-alter"
+	case "DROP TABLE":
+		return "table-drop"
+	case "CREATE DATABASE", "CREATE SCHEMA":
+		return "database-create"
+	case "ALTER DATABASE", "ALTER SCHEMA":
+		return "database-alter"
+	case "DROP DATABASE", "DROP SCHEMA":
+		return "database-drop"
+	default:
+		return "ddl"
+	}
+}
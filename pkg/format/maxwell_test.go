@@ -0,0 +1,76 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func TestMaxwellEncoder_TransactionMarkers(t *testing.T) {
+	msgs, err := MaxwellEncoder{}.Encode(updateTx())
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+
+	var first, last maxwellRow
+	_ = json.Unmarshal(msgs[0].Value, &first)
+	_ = json.Unmarshal(msgs[1].Value, &last)
+
+	if first.Type != "update" || first.Xid != 42 || first.Xoffset != 0 || first.Commit {
+		t.Fatalf("unexpected first row: %s", msgs[0].Value)
+	}
+	if last.Xid != 42 || last.Xoffset != 1 || !last.Commit {
+		t.Fatalf("last row must carry commit: %s", msgs[1].Value)
+	}
+	if first.Old["name"] != "old" || first.Data["name"] != "new" {
+		t.Fatalf("unexpected data/old: %s", msgs[0].Value)
+	}
+	if string(msgs[0].Key) != `{"database":"mydb","pk.id":1,"table":"users"}` {
+		t.Fatalf("unexpected key: %s", msgs[0].Key)
+	}
+}
+
+func TestMaxwellEncoder_Fragments(t *testing.T) {
+	tx := updateTx()
+	tests := []struct {
+		frag    model.Fragment
+		xoffset []int
+		commit  []bool
+	}{
+		{model.Fragment{Index: 0, RowOffset: 0}, []int{0, 1}, []bool{false, false}},
+		{model.Fragment{Index: 1, RowOffset: 2}, []int{2, 3}, []bool{false, false}},
+		{model.Fragment{Index: 2, RowOffset: 4, Last: true}, []int{4, 5}, []bool{false, true}},
+	}
+
+	for _, tt := range tests {
+		frag := model.NewTransactionFragment(tx.Source(), tx.Offset(), tx.Timestamp(), tx.TxID(), tx.Changes(), tx.Meta(), tt.frag)
+		msgs, err := MaxwellEncoder{}.Encode(frag)
+		if err != nil || len(msgs) != 2 {
+			t.Fatalf("fragment %d: unexpected result: %d %v", tt.frag.Index, len(msgs), err)
+		}
+		for i, msg := range msgs {
+			var r maxwellRow
+			_ = json.Unmarshal(msg.Value, &r)
+			if r.Xid != 42 || r.Xoffset != tt.xoffset[i] || r.Commit != tt.commit[i] {
+				t.Fatalf("fragment %d row %d: unexpected %s", tt.frag.Index, i, msg.Value)
+			}
+		}
+	}
+}
+
+func TestMaxwellEncoder_DDL(t *testing.T) {
+	ddl := model.NewBinlogDDLEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 7}, time.Unix(1700000000, 0), "", "mydb", "DROP TABLE IF EXISTS t1")
+
+	msgs, err := MaxwellEncoder{}.Encode(ddl)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected result: %d %v", len(msgs), err)
+	}
+
+	var m maxwellDDL
+	_ = json.Unmarshal(msgs[0].Value, &m)
+	if m.Type != "table-drop" || m.Database != "mydb" || m.Table != "t1" || m.Position != "binlog.000001:7" {
+		t.Fatalf("unexpected ddl: %s", msgs[0].Value)
+	}
+}
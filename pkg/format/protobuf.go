@@ -0,0 +1,180 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+type protobufCodec struct{}
+
+func (protobufCodec) schemaType() string { return SchemaTypeProtobuf }
+
+// wireHeader is the Confluent message index list; [0] (the first message in
+// the schema) is encoded as a single zero byte.
+func (protobufCodec) wireHeader() []byte { return []byte{0} }
+
+func protobufType(kind fieldKind) string {
+	switch kind {
+	case kindLong, kindTimestamp:
+		return "int64"
+	case kindDouble:
+		return "double"
+	case kindBytes:
+		return "bytes"
+	default:
+		return "string"
+	}
+}
+
+var (
+	protobufFieldLine    = regexp.MustCompile(`^\s*optional \w+ (\w+) = (\d+);`)
+	protobufReservedLine = regexp.MustCompile(`^\s*reserved ([\d, ]+);`)
+)
+
+// numberFields keeps the field numbers of prev, the latest registered
+// schema, for columns still present, so a dropped or inserted column does
+// not shift the others. New columns get numbers above any used before, and
+// numbers of dropped columns are reserved.
+func (protobufCodec) numberFields(prev string, fields []schemaField) ([]schemaField, []int) {
+	known := map[string]int{}
+	var reserved []int
+	next := 1
+	for _, line := range strings.Split(prev, "\n") {
+		if m := protobufFieldLine.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			known[m[1]] = n
+			next = max(next, n+1)
+		} else if m := protobufReservedLine.FindStringSubmatch(line); m != nil {
+			for _, s := range strings.Split(m[1], ",") {
+				if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+					reserved = append(reserved, n)
+					next = max(next, n+1)
+				}
+			}
+		}
+	}
+	if len(known) == 0 {
+		return fields, reserved
+	}
+
+	out := make([]schemaField, len(fields))
+	kept := map[string]bool{}
+	for i, f := range fields {
+		if n, ok := known[f.name]; ok {
+			f.number = n
+			kept[f.name] = true
+		} else {
+			f.number = next
+			next++
+		}
+		out[i] = f
+	}
+	for name, n := range known {
+		if !kept[name] {
+			reserved = append(reserved, n)
+		}
+	}
+	sort.Ints(reserved)
+	return out, reserved
+}
+
+func protobufMessage(b *strings.Builder, name string, fields []schemaField, reserved []int) {
+	fmt.Fprintf(b, "message %s {\n", name)
+	if len(reserved) > 0 {
+		nums := make([]string, len(reserved))
+		for i, n := range reserved {
+			nums[i] = strconv.Itoa(n)
+		}
+		fmt.Fprintf(b, "  reserved %s;\n", strings.Join(nums, ", "))
+	}
+	for _, f := range fields {
+		fmt.Fprintf(b, "  optional %s %s = %d;\n", protobufType(f.kind), f.name, f.number)
+	}
+	b.WriteString("}\n")
+}
+
+func (protobufCodec) keySchema(namespace string, fields []schemaField, reserved []int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "syntax = \"proto3\";\npackage %s;\n\n", namespace)
+	protobufMessage(&b, "Key", fields, reserved)
+	return b.String()
+}
+
+func (protobufCodec) valueSchema(namespace string, fields []schemaField, reserved []int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "syntax = \"proto3\";\npackage %s;\n\n", namespace)
+	b.WriteString("message Envelope {\n" +
+		"  Value before = 1;\n" +
+		"  Value after = 2;\n" +
+		"  string op = 3;\n" +
+		"  string tx_id = 4;\n" +
+		"  string offset = 5;\n" +
+		"  int64 ts_ms = 6;\n" +
+		"}\n\n")
+	protobufMessage(&b, "Value", fields, reserved)
+	return b.String()
+}
+
+func (protobufCodec) encodeKey(fields []schemaField, pk map[string]any) []byte {
+	return protobufAppendRow(nil, fields, pk)
+}
+
+func (protobufCodec) encodeValue(fields []schemaField, env envelope) []byte {
+	var out []byte
+	for i, row := range []map[string]any{env.before, env.after} {
+		if row == nil {
+			continue
+		}
+		out = protobufAppendBytes(out, i+1, protobufAppendRow(nil, fields, row))
+	}
+	out = protobufAppendBytes(out, 3, []byte(env.op))
+	out = protobufAppendBytes(out, 4, []byte(env.txID))
+	out = protobufAppendBytes(out, 5, []byte(env.offset))
+	out = protobufAppendTag(out, 6, wireVarint)
+	return binary.AppendUvarint(out, uint64(env.tsMs))
+}
+
+func protobufAppendRow(out []byte, fields []schemaField, row map[string]any) []byte {
+	for _, f := range fields {
+		v, ok := convert(f.kind, row[f.column])
+		if !ok {
+			continue
+		}
+
+		switch x := v.(type) {
+		case int64:
+			out = protobufAppendTag(out, f.number, wireVarint)
+			out = binary.AppendUvarint(out, uint64(x))
+		case float64:
+			out = protobufAppendTag(out, f.number, wireFixed64)
+			out = binary.LittleEndian.AppendUint64(out, math.Float64bits(x))
+		case []byte:
+			out = protobufAppendBytes(out, f.number, x)
+		case string:
+			out = protobufAppendBytes(out, f.number, []byte(x))
+		}
+	}
+	return out
+}
+
+func protobufAppendTag(out []byte, num, wire int) []byte {
+	return binary.AppendUvarint(out, uint64(num)<<3|uint64(wire))
+}
+
+func protobufAppendBytes(out []byte, num int, b []byte) []byte {
+	out = protobufAppendTag(out, num, wireBytes)
+	out = binary.AppendUvarint(out, uint64(len(b)))
+	return append(out, b...)
+}
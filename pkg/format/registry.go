@@ -0,0 +1,187 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// Registry is a client for the Confluent Schema Registry REST API.
+type Registry struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewRegistry(baseURL string) *Registry {
+	return &Registry{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type registryRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// Register registers schema under subject and returns its global ID. The
+// registry returns the existing ID when the schema is already registered.
+func (r *Registry) Register(subject, schemaType, schema string) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if _, err := r.post(path, schemaType, schema, &resp); err != nil {
+		return 0, fmt.Errorf("register %s: %w", subject, err)
+	}
+	return resp.ID, nil
+}
+
+// Compatible checks schema against the latest version of subject using the
+// compatibility level configured in the registry. A subject without versions
+// accepts any schema.
+func (r *Registry) Compatible(subject, schemaType, schema string) (bool, error) {
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	status, err := r.post(path, schemaType, schema, &resp)
+	if status == http.StatusNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check compatibility %s: %w", subject, err)
+	}
+	return resp.IsCompatible, nil
+}
+
+// Latest returns the schema of the latest version of subject, or "" when the
+// subject has none.
+func (r *Registry) Latest(subject string) (string, error) {
+	resp, err := r.client.Get(r.baseURL + "/subjects/" + url.PathEscape(subject) + "/versions/latest")
+	if err != nil {
+		return "", fmt.Errorf("latest %s: %w", subject, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("latest %s: %s: %s", subject, resp.Status, strings.TrimSpace(string(b)))
+	}
+	var out struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return "", fmt.Errorf("latest %s: %w", subject, err)
+	}
+	return out.Schema, nil
+}
+
+func (r *Registry) post(path, schemaType, schema string, out any) (int, error) {
+	req := registryRequest{Schema: schema}
+	if schemaType != SchemaTypeAvro {
+		req.SchemaType = schemaType
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := r.client.Post(r.baseURL+path, "application/vnd.schemaregistry.v1+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp.StatusCode, json.Unmarshal(b, out)
+}
+
+// schemaCache registers generated schemas once per subject and re-checks
+// compatibility whenever the generated schema changes (e.g. after DDL).
+type schemaCache struct {
+	registry   *Registry
+	schemaType string
+
+	mu      sync.Mutex
+	entries map[string]cachedSchema
+}
+
+type cachedSchema struct {
+	schema string
+	id     int
+}
+
+func newSchemaCache(registry *Registry, schemaType string) *schemaCache {
+	return &schemaCache{
+		registry:   registry,
+		schemaType: schemaType,
+		entries:    make(map[string]cachedSchema),
+	}
+}
+
+func (c *schemaCache) resolve(subject, schema string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[subject]; ok && e.schema == schema {
+		return e.id, nil
+	}
+
+	ok, err := c.registry.Compatible(subject, c.schemaType, schema)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("schema for %s is not compatible with the latest registered version", subject)
+	}
+
+	id, err := c.registry.Register(subject, c.schemaType, schema)
+	if err != nil {
+		return 0, err
+	}
+
+	c.entries[subject] = cachedSchema{schema: schema, id: id}
+	return id, nil
+}
+
+// latest returns the schema last registered for subject, asking the registry
+// until this process has registered one itself.
+func (c *schemaCache) latest(subject string) (string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[subject]
+	c.mu.Unlock()
+	if ok {
+		return e.schema, nil
+	}
+	return c.registry.Latest(subject)
+}
+
+// confluentHeader is the magic byte followed by the big-endian schema ID.
+func confluentHeader(id int) []byte {
+	return []byte{0, byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+}
@@ -0,0 +1,144 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// RegistryEncoder encodes row changes with schemas generated from the captured
+// table columns and registered with a schema registry (Confluent wire format).
+// The schema is regenerated when the table's columns change after DDL, and the
+// new version is only used once the registry reports it compatible.
+type RegistryEncoder struct {
+	serverName string
+	codec      schemaCodec
+	cache      *schemaCache
+}
+
+// schemaCodec generates schemas and encodes payloads for one serialization
+// format.
+type schemaCodec interface {
+	schemaType() string
+	keySchema(namespace string, fields []schemaField, reserved []int) string
+	valueSchema(namespace string, fields []schemaField, reserved []int) string
+	encodeKey(fields []schemaField, pk map[string]any) []byte
+	encodeValue(fields []schemaField, env envelope) []byte
+	// wireHeader returns the bytes written between the schema ID and payload.
+	wireHeader() []byte
+}
+
+// fieldNumberer is implemented by codecs whose wire format identifies fields
+// by number, so numbers must survive columns being dropped or inserted.
+type fieldNumberer interface {
+	// numberFields renumbers fields after prev, the latest registered schema,
+	// and returns them with the numbers to reserve.
+	numberFields(prev string, fields []schemaField) ([]schemaField, []int)
+}
+
+type envelope struct {
+	before map[string]any
+	after  map[string]any
+	op     string
+	txID   string
+	offset string
+	tsMs   int64
+}
+
+func NewAvroEncoder(serverName string, registry *Registry) *RegistryEncoder {
+	return newRegistryEncoder(serverName, registry, avroCodec{})
+}
+
+func NewProtobufEncoder(serverName string, registry *Registry) *RegistryEncoder {
+	return newRegistryEncoder(serverName, registry, protobufCodec{})
+}
+
+func newRegistryEncoder(serverName string, registry *Registry, codec schemaCodec) *RegistryEncoder {
+	return &RegistryEncoder{
+		serverName: serverName,
+		codec:      codec,
+		cache:      newSchemaCache(registry, codec.schemaType()),
+	}
+}
+
+func (r *RegistryEncoder) Encode(evt model.Event) ([]Message, error) {
+	e, ok := evt.(model.RowChangeEvent)
+	if !ok {
+		return nil, nil
+	}
+
+	var msgs []Message
+	for _, change := range e.Changes() {
+		namespace := sanitizeName(r.serverName) + "." + sanitizeName(change.Schema) + "." + sanitizeName(change.Table)
+		valueSubject := subjectName(r.serverName, change.Schema, change.Table, "value")
+		fields, reserved, err := r.layout(valueSubject, schemaFields(change.Columns))
+		if err != nil {
+			return nil, err
+		}
+
+		valueID, err := r.cache.resolve(valueSubject, r.codec.valueSchema(namespace, fields, reserved))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range changeRows(change) {
+			for _, values := range []map[string]any{row.Before, row.After} {
+				if err := checkRange(fields, values); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", change.Schema, change.Table, err)
+				}
+			}
+
+			var key []byte
+			if len(row.PK) > 0 {
+				keySubject := subjectName(r.serverName, change.Schema, change.Table, "key")
+				keyFields, keyReserved, err := r.layout(keySubject, schemaFields(pkColumns(change.Columns, row.PK)))
+				if err != nil {
+					return nil, err
+				}
+				if err := checkRange(keyFields, row.PK); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", change.Schema, change.Table, err)
+				}
+				keyID, err := r.cache.resolve(keySubject, r.codec.keySchema(namespace, keyFields, keyReserved))
+				if err != nil {
+					return nil, err
+				}
+				key = r.frame(keyID, r.codec.encodeKey(keyFields, row.PK))
+			}
+
+			value := r.frame(valueID, r.codec.encodeValue(fields, envelope{
+				before: row.Before,
+				after:  row.After,
+				op:     string(change.Op),
+				txID:   e.TxID(),
+				offset: evt.Offset().String(),
+				tsMs:   evt.Timestamp().UnixMilli(),
+			}))
+
+			msgs = append(msgs, Message{Key: key, Value: value})
+		}
+	}
+
+	return msgs, nil
+}
+
+// layout numbers fields after the latest schema registered for subject when
+// the codec needs stable field numbers. Numbering from the registered schema
+// means a rejected evolution leaves the next attempt unaffected.
+func (r *RegistryEncoder) layout(subject string, fields []schemaField) ([]schemaField, []int, error) {
+	n, ok := r.codec.(fieldNumberer)
+	if !ok {
+		return fields, nil, nil
+	}
+	prev, err := r.cache.latest(subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, reserved := n.numberFields(prev, fields)
+	return fields, reserved, nil
+}
+
+func (r *RegistryEncoder) frame(id int, payload []byte) []byte {
+	out := confluentHeader(id)
+	out = append(out, r.codec.wireHeader()...)
+	return append(out, payload...)
+}
@@ -0,0 +1,222 @@
+package format
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// fakeRegistry is a minimal in-memory stand-in for a Confluent schema registry.
+type fakeRegistry struct {
+	mu         sync.Mutex
+	ids        map[string]int
+	subjects   map[string][]string
+	compatible bool
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *httptest.Server) {
+	f := &fakeRegistry{ids: map[string]int{}, subjects: map[string][]string{}, compatible: true}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+			if versions := f.subjects[parts[1]]; len(parts) == 4 && len(versions) > 0 {
+				_ = json.NewEncoder(w).Encode(map[string]string{"schema": versions[len(versions)-1]})
+				return
+			}
+			http.Error(w, `{"error_code":40401}`, http.StatusNotFound)
+			return
+		}
+
+		var req registryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case parts[0] == "subjects" && len(parts) == 3:
+			subject := parts[1]
+			id, ok := f.ids[req.Schema]
+			if !ok {
+				id = len(f.ids) + 1
+				f.ids[req.Schema] = id
+				f.subjects[subject] = append(f.subjects[subject], req.Schema)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
+
+		case parts[0] == "compatibility":
+			if len(f.subjects[parts[2]]) == 0 {
+				http.Error(w, `{"error_code":40401}`, http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": f.compatible})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func registryTx(cols []model.Column, after map[string]any) *model.TransactionEvent {
+	return model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 10}, time.Unix(1700000000, 0), "tx-1", []model.RowChange{{
+		Schema:  "mydb",
+		Table:   "users",
+		Op:      model.OpInsert,
+		Columns: cols,
+		Rows:    []model.RowData{{PK: map[string]any{"id": after["id"]}, After: after}},
+	}}, model.TxMeta{})
+}
+
+func TestAvroEncoder_WireFormat(t *testing.T) {
+	f, srv := newFakeRegistry(t)
+	enc := NewAvroEncoder("dbserver1", NewRegistry(srv.URL))
+
+	cols := []model.Column{{Name: "id", Type: "bigint"}, {Name: "name", Type: "varchar"}}
+	msgs, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(1), "name": "al"}))
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+
+	v := msgs[0].Value
+	if v[0] != 0 {
+		t.Fatalf("missing magic byte")
+	}
+	if id := binary.BigEndian.Uint32(v[1:5]); id == 0 {
+		t.Fatalf("missing schema id")
+	}
+
+	// before=null(0), after=union 1 -> id union 1, long 1 -> name union 1, "al"
+	want := []byte{0x00, 0x02, 0x02, 0x02, 0x02, 0x04, 'a', 'l'}
+	if string(v[5:5+len(want)]) != string(want) {
+		t.Fatalf("unexpected avro payload: %x", v[5:])
+	}
+
+	if len(f.subjects["dbserver1.mydb.users-value"]) != 1 || len(f.subjects["dbserver1.mydb.users-key"]) != 1 {
+		t.Fatalf("subjects not registered: %v", f.subjects)
+	}
+}
+
+func TestRegistryEncoder_SchemaEvolution(t *testing.T) {
+	f, srv := newFakeRegistry(t)
+	enc := NewAvroEncoder("dbserver1", NewRegistry(srv.URL))
+
+	cols := []model.Column{{Name: "id", Type: "bigint"}}
+	if _, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(1)})); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	// ALTER TABLE ADD COLUMN registers a second version
+	cols = append(cols, model.Column{Name: "note", Type: "varchar"})
+	if _, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(2), "note": "x"})); err != nil {
+		t.Fatalf("encode after ddl failed: %v", err)
+	}
+	f.mu.Lock()
+	n := len(f.subjects["dbserver1.mydb.users-value"])
+	f.mu.Unlock()
+	if n != 2 {
+		t.Fatalf("expected 2 schema versions, got %d", n)
+	}
+
+	f.mu.Lock()
+	f.compatible = false
+	f.mu.Unlock()
+	cols = []model.Column{{Name: "id", Type: "varchar"}}
+	if _, err := enc.Encode(registryTx(cols, map[string]any{"id": "3"})); err == nil {
+		t.Fatal("expected incompatible schema to be rejected")
+	}
+}
+
+func TestProtobufEncoder_WireFormat(t *testing.T) {
+	f, srv := newFakeRegistry(t)
+	enc := NewProtobufEncoder("dbserver1", NewRegistry(srv.URL))
+
+	cols := []model.Column{{Name: "id", Type: "bigint"}}
+	msgs, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(1)}))
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	v := msgs[0].Value
+	if v[0] != 0 || v[5] != 0 {
+		t.Fatalf("unexpected confluent header: %x", v[:6])
+	}
+
+	// after (field 2, len 2) -> id (field 1, varint 1)
+	want := []byte{0x12, 0x02, 0x08, 0x01}
+	if string(v[6:6+len(want)]) != string(want) {
+		t.Fatalf("unexpected protobuf payload: %x", v[6:])
+	}
+
+	schema := f.subjects["dbserver1.mydb.users-value"][0]
+	if !strings.Contains(schema, "optional int64 id = 1;") {
+		t.Fatalf("unexpected proto schema:\n%s", schema)
+	}
+}
+
+func TestProtobufEncoder_StableFieldNumbers(t *testing.T) {
+	f, srv := newFakeRegistry(t)
+
+	cols := []model.Column{{Name: "id", Type: "bigint"}, {Name: "name", Type: "varchar"}, {Name: "email", Type: "varchar"}}
+	enc := NewProtobufEncoder("dbserver1", NewRegistry(srv.URL))
+	if _, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(1), "name": "al", "email": "a@x"})); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	// DROP COLUMN name, then ADD COLUMN nick AFTER id; a fresh encoder reads
+	// the numbering back from the registry.
+	cols = []model.Column{{Name: "id", Type: "bigint"}, {Name: "nick", Type: "varchar"}, {Name: "email", Type: "varchar"}}
+	enc = NewProtobufEncoder("dbserver1", NewRegistry(srv.URL))
+	msgs, err := enc.Encode(registryTx(cols, map[string]any{"id": int64(2), "nick": "b", "email": "b@x"}))
+	if err != nil {
+		t.Fatalf("encode after ddl failed: %v", err)
+	}
+
+	f.mu.Lock()
+	versions := f.subjects["dbserver1.mydb.users-value"]
+	f.mu.Unlock()
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 schema versions, got %d", len(versions))
+	}
+	for _, want := range []string{"reserved 2;", "optional int64 id = 1;", "optional string email = 3;", "optional string nick = 4;"} {
+		if !strings.Contains(versions[1], want) {
+			t.Fatalf("schema missing %q:\n%s", want, versions[1])
+		}
+	}
+
+	// after (field 2) -> id=2 (field 1), nick "b" (field 4), email "b@x" (field 3)
+	want := []byte{0x12, 0x0a, 0x08, 0x02, 0x22, 0x01, 'b', 0x1a, 0x03, 'b', '@', 'x'}
+	if v := msgs[0].Value; string(v[6:6+len(want)]) != string(want) {
+		t.Fatalf("unexpected protobuf payload: %x", v[6:])
+	}
+}
+
+func TestRegistryEncoder_UnsignedOverflow(t *testing.T) {
+	for _, enc := range []func(string, *Registry) *RegistryEncoder{NewAvroEncoder, NewProtobufEncoder} {
+		_, srv := newFakeRegistry(t)
+		cols := []model.Column{{Name: "id", Type: "bigint"}, {Name: "n", Type: "bigint", SQLType: "bigint unsigned"}}
+		_, err := enc("dbserver1", NewRegistry(srv.URL)).Encode(registryTx(cols, map[string]any{"id": int64(1), "n": uint64(1 << 63)}))
+		if err == nil || !strings.Contains(err.Error(), "overflows") {
+			t.Fatalf("expected overflow error, got %v", err)
+		}
+	}
+}
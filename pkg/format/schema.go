@@ -0,0 +1,195 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// fieldKind is the encoding used for a column in generated Avro and Protobuf
+// schemas.
+type fieldKind int
+
+const (
+	kindLong fieldKind = iota
+	kindDouble
+	kindString
+	kindBytes
+	kindTimestamp
+)
+
+type schemaField struct {
+	name   string
+	column string
+	kind   fieldKind
+	// number is the Protobuf field number, by default the column position.
+	number int
+}
+
+func kindOf(c model.Column) fieldKind {
+	switch c.Type {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "year", "bit", "enum", "set":
+		return kindLong
+	case "float", "double":
+		return kindDouble
+	case "datetime", "timestamp":
+		return kindTimestamp
+	case "blob", "geometry":
+		return kindBytes
+	default:
+		return kindString
+	}
+}
+
+func schemaFields(cols []model.Column) []schemaField {
+	fields := make([]schemaField, len(cols))
+	for i, c := range cols {
+		fields[i] = schemaField{name: sanitizeName(c.Name), column: c.Name, kind: kindOf(c), number: i + 1}
+	}
+	return fields
+}
+
+// pkColumns returns the columns backing the row's primary key in table order.
+func pkColumns(cols []model.Column, pk map[string]any) []model.Column {
+	var out []model.Column
+	for _, c := range cols {
+		if _, ok := pk[c.Name]; ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// sanitizeName maps identifiers to [A-Za-z_][A-Za-z0-9_]*, which both Avro
+// and Protobuf accept.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func subjectName(server, schema, table, suffix string) string {
+	return fmt.Sprintf("%s.%s.%s-%s", server, schema, table, suffix)
+}
+
+// convert returns v in the Go type used on the wire for kind, or false when
+// the value is NULL or cannot be represented (e.g. zero dates).
+func convert(kind fieldKind, v any) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	switch kind {
+	case kindLong:
+		switch x := v.(type) {
+		case int:
+			return int64(x), true
+		case int8:
+			return int64(x), true
+		case int16:
+			return int64(x), true
+		case int32:
+			return int64(x), true
+		case int64:
+			return x, true
+		case uint8:
+			return int64(x), true
+		case uint16:
+			return int64(x), true
+		case uint32:
+			return int64(x), true
+		case uint64:
+			if x > math.MaxInt64 {
+				return nil, false
+			}
+			return int64(x), true
+		}
+	case kindDouble:
+		switch x := v.(type) {
+		case float32:
+			return float64(x), true
+		case float64:
+			return x, true
+		}
+	case kindTimestamp:
+		if t, ok := v.(time.Time); ok {
+			return t.UnixMilli(), true
+		}
+	case kindBytes:
+		switch x := v.(type) {
+		case []byte:
+			return x, true
+		case string:
+			return []byte(x), true
+		}
+	default:
+		switch x := v.(type) {
+		case string:
+			return x, true
+		case []byte:
+			return string(x), true
+		case time.Time:
+			return x.Format(time.RFC3339Nano), true
+		default:
+			return fmt.Sprint(x), true
+		}
+	}
+	return nil, false
+}
+
+// checkRange reports values of row that a long field cannot hold, such as
+// BIGINT UNSIGNED values above MaxInt64, instead of letting convert drop them.
+func checkRange(fields []schemaField, row map[string]any) error {
+	for _, f := range fields {
+		if x, ok := row[f.column].(uint64); ok && f.kind == kindLong && x > math.MaxInt64 {
+			return fmt.Errorf("column %s: %d overflows a long", f.column, x)
+		}
+	}
+	return nil
+}
+
+// changedColumns returns the old values of columns whose value differs
+// between before and after.
+func changedColumns(before, after map[string]any) map[string]any {
+	old := map[string]any{}
+	for k, v := range before {
+		if !reflect.DeepEqual(v, after[k]) {
+			old[k] = v
+		}
+	}
+	return old
+}
+
+func pkNames(cols []model.Column, pk map[string]any) []string {
+	names := []string{}
+	for _, c := range pkColumns(cols, pk) {
+		names = append(names, c.Name)
+	}
+	if len(names) == 0 {
+		for k := range pk {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+	}
+	return names
+}
@@ -2,13 +2,16 @@ package inspector
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
 	"github.com/cursus-io/tabellarius/pkg/model"
 	"github.com/cursus-io/tabellarius/pkg/util"
 
@@ -19,35 +22,79 @@ import (
 type BinlogInspector struct {
 	db       *sql.DB
 	dbType   model.DatabaseType
+	schema   string
 	dsn      string
 	serverID uint32
+	opts     Options
 
 	host     string
 	port     uint16
 	user     string
 	password string
+	tls      *tls.Config
 
 	offsetPath  string
 	currentFile string
 
-	tableMeta   map[string]*tableMeta
+	tableMeta map[string]*tableMeta
+	// removed holds the tables a reload took out of tables:, whose rows are
+	// dropped rather than emitted with default columns.
+	removed     map[string]bool
 	currentTxID string
+	inTx        bool
+	// txStart is where the current transaction's event group began and
+	// txMeta what is known about its origin so far.
+	txStart model.MySQLOffset
+	txMeta  model.TxMeta
+	// xa reports that the current transaction was opened by XA START.
+	xa bool
+
+	// statement is the SQL behind the rows events that follow it, as
+	// recorded by a ROWS_QUERY or ANNOTATE_ROWS event.
+	statement string
+
+	// committed is the offset right after the last emitted transaction.
+	committed model.MySQLOffset
+	gtidSet   mysql.GTIDSet
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	reconnects *metrics.Counter
+
+	// mu guards a pending reload and the function waking the stream for it.
+	mu      sync.Mutex
+	pending *Update
+	wake    context.CancelFunc
 }
 
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
 var _ Inspector[model.Event] = (*BinlogInspector)(nil)
 
-func NewBinlogInspector(db *sql.DB, dbType model.DatabaseType, schema, dsn, offsetPath string, serverID uint32, tables []config.Table) (*BinlogInspector, error) {
+func NewBinlogInspector(db *sql.DB, dbType model.DatabaseType, schema, dsn, offsetPath string, serverID uint32, tables []config.Table, opts Options) (*BinlogInspector, error) {
 	if !dbType.IsBinlogBased() {
 		return nil, fmt.Errorf("db %s is not binlog based", dbType)
 	}
+	reg := opts.Metrics
+	if reg == nil {
+		reg = metrics.Default
+	}
 
 	b := &BinlogInspector{
 		db:         db,
 		dbType:     dbType,
+		schema:     schema,
 		dsn:        dsn,
 		serverID:   serverID,
+		opts:       opts,
 		offsetPath: offsetPath,
 		tableMeta:  make(map[string]*tableMeta),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		reconnects: reg.Counter("binlog_reconnects_total"),
 	}
 
 	for _, t := range tables {
@@ -65,126 +112,460 @@ func NewBinlogInspector(db *sql.DB, dbType model.DatabaseType, schema, dsn, offs
 	if off, ok := util.LoadJSON[model.MySQLOffset](offsetPath); ok {
 		b.currentFile = off.File
 	}
+	if opts.Start != (model.MySQLOffset{}) {
+		b.committed = opts.Start
+		b.currentFile = opts.Start.File
+	}
 
 	return b, nil
 }
 
+// Start streams binlog events into out until ctx is cancelled. Transient
+// stream errors reconnect with capped exponential backoff from the last
+// committed transaction; fatal ones (see IsFatal) are returned.
 func (b *BinlogInspector) Start(ctx context.Context, out chan<- model.Event) error {
-	log.Printf("[binlog] connect %s@%s:%d", b.user, b.host, b.port)
+	backoff := b.minBackoff
+
+	for {
+		progressed, err := b.stream(ctx, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if IsFatal(err) {
+			log.Printf("[binlog] fatal error: %v", err)
+			return fmt.Errorf("binlog stream: %w", err)
+		}
+
+		if progressed {
+			backoff = b.minBackoff
+		}
+
+		b.abortTx(out)
+		b.reconnects.Inc()
+
+		log.Printf("[binlog] stream error: %v, reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff = nextBackoff(backoff, b.maxBackoff)
+	}
+}
+
+// stream runs one replication connection until it fails. progressed reports
+// whether any event was received, so a flapping connection keeps backing off.
+func (b *BinlogInspector) stream(ctx context.Context, out chan<- model.Event) (progressed bool, err error) {
+	log.Printf("[binlog] connect %s@%s tls=%v", b.user, b.addr(), b.tls != nil)
+
+	syncer := replication.NewBinlogSyncer(b.syncerConfig())
+	defer syncer.Close()
+
+	// waitCtx is cancelled by Reload, so a reload is applied even while
+	// the stream is idle
+	waitCtx, wake := b.armWake(ctx)
+	defer func() { wake() }()
+
+	if err := b.applyPending(ctx, out); err != nil {
+		return false, err
+	}
+	streamer, err := b.startSync(ctx, syncer, out)
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		ev, err := streamer.GetEvent(waitCtx)
+		switch {
+		case err != nil && ctx.Err() == nil && waitCtx.Err() != nil:
+			waitCtx, wake = b.armWake(ctx)
+		case err != nil:
+			return progressed, err
+		default:
+			progressed = true
+			b.handleEvent(out, ev)
+		}
+
+		if !b.inTx {
+			if err := b.applyPending(ctx, out); err != nil {
+				return progressed, err
+			}
+		}
+	}
+}
 
-	cfg := replication.BinlogSyncerConfig{
+func (b *BinlogInspector) syncerConfig() replication.BinlogSyncerConfig {
+	return replication.BinlogSyncerConfig{
 		ServerID:   b.serverID,
 		Flavor:     b.dbType.BinlogFlavor(),
 		Host:       b.host,
 		Port:       b.port,
 		User:       b.user,
 		Password:   b.password,
+		TLSConfig:  b.tls,
 		UseDecimal: true,
 		ParseTime:  true,
-	}
 
-	syncer := replication.NewBinlogSyncer(cfg)
+		// MariaDB 11.4+ may log events with a zero log position; the syncer
+		// recomputes it, and asks for ANNOTATE_ROWS events, which carry the
+		// statements captured for database.statements.
+		FillZeroLogPos: b.dbType == model.MariaDB,
 
-	var startPos mysql.Position
-	if off, ok := util.LoadJSON[model.MySQLOffset](b.offsetPath); ok {
-		startPos = mysql.Position{Name: off.File, Pos: off.Pos}
-		b.currentFile = off.File
+		// Reconnects are handled by Start so they resume from a committed
+		// transaction rather than mid-transaction.
+		DisableRetrySync: true,
 	}
+}
 
-	streamer, err := syncer.StartSync(startPos)
+func (b *BinlogInspector) armWake(ctx context.Context) (context.Context, context.CancelFunc) {
+	waitCtx, wake := context.WithCancel(ctx)
+
+	b.mu.Lock()
+	b.wake = wake
+	b.mu.Unlock()
+	return waitCtx, wake
+}
+
+// startSync starts replication from the last committed transaction, using its
+// GTID set when known and its file position otherwise.
+func (b *BinlogInspector) startSync(ctx context.Context, syncer *replication.BinlogSyncer, out chan<- model.Event) (*replication.BinlogStreamer, error) {
+	off, err := b.checkPurged(ctx, out, b.resumePoint())
 	if err != nil {
-		return err
+		return nil, err
 	}
+	b.currentFile = off.File
+	b.gtidSet = nil
 
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
+	if off.GTIDSet != "" {
+		gset, err := mysql.ParseGTIDSet(b.dbType.BinlogFlavor(), off.GTIDSet)
+		if err != nil {
+			return nil, fatal(fmt.Errorf("invalid gtid set in offset: %w", err))
+		}
+		b.gtidSet = gset
 
-		default:
-			ev, err := streamer.GetEvent(ctx)
-			if err != nil {
-				log.Printf("[binlog] error: %v", err)
-				time.Sleep(300 * time.Millisecond)
-				continue
-			}
+		log.Printf("[binlog] resume from gtid set %s", off.GTIDSet)
+		return syncer.StartSyncGTID(gset.Clone())
+	}
 
-			var txID string
-			switch e := ev.Event.(type) {
-			case *replication.XIDEvent:
-				txID = fmt.Sprintf("xid:%d", e.XID)
-			case *replication.GTIDEvent:
-				txID = fmt.Sprintf("gtid:%s:%d", string(e.SID), e.GNO)
-			case *replication.QueryEvent:
-				query := string(e.Query)
-				src := model.SourceType(b.dbType)
-
-				if isSystemSchema(e.Schema) {
-					continue
-				}
-
-				if query == "BEGIN" || query == "COMMIT" || query == "ROLLBACK" {
-					continue
-				}
-
-				if strings.Contains(query, "ALTER TABLE") || strings.Contains(query, "CREATE TABLE") {
-					log.Printf("[schema] DDL detected: %s. Refreshing metadata...", query)
-
-					for key := range b.tableMeta {
-						schema, table := splitKey(key)
-						cols := b.fetchColumns(schema, table)
-
-						if len(cols) > 0 {
-							b.tableMeta[key].columns = cols
-							b.updatePKIndex(key)
-						}
-					}
-				}
-
-				if !isDML(e) {
-					eventTime := time.Unix(int64(ev.Header.Timestamp), 0)
-					offset := model.MySQLOffset{
-						File: b.currentFile,
-						Pos:  ev.Header.LogPos,
-					}
-					out <- model.NewBinlogDDLEvent(src, offset, eventTime, b.currentTxID, query)
-				} else {
-					if b.currentTxID == "" {
-						b.currentTxID = fmt.Sprintf("query:%d", ev.Header.LogPos)
-					}
-				}
-			}
+	if off.File != "" {
+		log.Printf("[binlog] resume from %s", off)
+	}
+	return syncer.StartSync(mysql.Position{Name: off.File, Pos: off.Pos})
+}
+
+// resumePoint returns the position right after the last transaction emitted
+// by this inspector, falling back to the persisted offset.
+func (b *BinlogInspector) resumePoint() model.MySQLOffset {
+	if b.committed.File != "" || b.committed.GTIDSet != "" {
+		return b.committed
+	}
+	off, _ := util.LoadJSON[model.MySQLOffset](b.offsetPath)
+	return off
+}
+
+// abortTx discards the open transaction, e.g. when the stream broke; it is
+// replayed in full after reconnecting.
+func (b *BinlogInspector) abortTx(out chan<- model.Event) {
+	if b.inTx {
+		out <- model.NewTransactionBoundaryEvent(model.SourceType(b.dbType), b.resumePoint(), time.Now(), b.currentTxID, model.TxAbort)
+	}
+	b.resetTx()
+}
+
+func (b *BinlogInspector) resetTx() {
+	b.currentTxID = ""
+	b.inTx = false
+	b.txStart = model.MySQLOffset{}
+	b.txMeta = model.TxMeta{}
+	b.xa = false
+	b.statement = ""
+}
+
+// startGroup records the GTID event opening a transaction group.
+func (b *BinlogInspector) startGroup(out chan<- model.Event, h *replication.EventHeader, gtid string) {
+	if b.inTx {
+		// the previous transaction was never terminated (e.g. the
+		// source crashed while writing it)
+		log.Printf("[binlog] transaction %s truncated, discarding", b.currentTxID)
+		b.abortTx(out)
+	}
+	b.resetTx()
+	b.txStart = b.eventStart(h)
+	b.txMeta = model.TxMeta{GTID: gtid, ServerID: h.ServerID}
+}
+
+// txIdentity is the GTID of the current transaction when the server assigns
+// one, and the position its event group starts at otherwise.
+func (b *BinlogInspector) txIdentity(h *replication.EventHeader) string {
+	if b.txMeta.GTID != "" {
+		return b.txMeta.GTID
+	}
+	if b.txStart.File == "" && b.txStart.Pos == 0 {
+		b.txStart = b.eventStart(h)
+	}
+	return b.txStart.String()
+}
+
+// eventStart returns the position of the first byte of the event.
+func (b *BinlogInspector) eventStart(h *replication.EventHeader) model.MySQLOffset {
+	pos := h.LogPos
+	if h.EventSize <= pos {
+		pos -= h.EventSize
+	}
+	return model.MySQLOffset{File: b.currentFile, Pos: pos}
+}
+
+// beginTx opens a transaction at h unless one is already open. Transactions
+// without a BEGIN event (e.g. DDL) are opened implicitly. The BEGIN boundary
+// carries the point to resume from should the transaction be cut short: the
+// previous commit, or where its event group started before the first one.
+func (b *BinlogInspector) beginTx(out chan<- model.Event, h *replication.EventHeader) {
+	if b.inTx {
+		return
+	}
+	if b.currentTxID == "" {
+		b.currentTxID = b.txIdentity(h)
+	}
+	if b.txMeta.ServerID == 0 {
+		b.txMeta.ServerID = h.ServerID
+	}
+	b.inTx = true
+
+	offset := b.resumePoint()
+	if offset == (model.MySQLOffset{}) {
+		offset = b.txStart
+	}
+	out <- model.NewTransactionBoundaryEvent(model.SourceType(b.dbType), offset, time.Unix(int64(h.Timestamp), 0), b.currentTxID, model.TxBegin)
+}
+
+// endTx closes the open transaction with kind (TxCommit or TxRollback). The
+// position after h becomes the resume point either way: a rolled back
+// transaction has still been consumed.
+func (b *BinlogInspector) endTx(out chan<- model.Event, h *replication.EventHeader, kind model.TxBoundaryKind) {
+	offset := b.commitOffset(h.LogPos)
+	if b.inTx {
+		eventTime := time.Unix(int64(h.Timestamp), 0)
+
+		meta := b.txMeta
+		if meta.CommitTime.IsZero() {
+			meta.CommitTime = eventTime
+		}
+		out <- model.NewTransactionEndEvent(model.SourceType(b.dbType), offset, eventTime, b.currentTxID, kind, meta)
+	}
+	b.resetTx()
+}
+
+func (b *BinlogInspector) savepoint(out chan<- model.Event, h *replication.EventHeader, kind model.TxBoundaryKind, name string) {
+	b.beginTx(out, h)
+
+	offset := model.MySQLOffset{File: b.currentFile, Pos: h.LogPos}
+	out <- model.NewSavepointEvent(model.SourceType(b.dbType), offset, time.Unix(int64(h.Timestamp), 0), b.currentTxID, kind, name)
+}
+
+// commitOffset returns the offset of a transaction ending at pos and records
+// it as the resume point.
+func (b *BinlogInspector) commitOffset(pos uint32) model.MySQLOffset {
+	if b.gtidSet != nil && b.txMeta.GTID != "" {
+		if err := b.gtidSet.Update(b.txMeta.GTID); err != nil {
+			log.Printf("[binlog] failed to track gtid %s: %v", b.txMeta.GTID, err)
+			b.gtidSet = nil
+		}
+	}
+
+	offset := model.MySQLOffset{File: b.currentFile, Pos: pos}
+	if b.gtidSet != nil && !b.gtidSet.IsEmpty() {
+		offset.GTIDSet = b.gtidSet.String()
+		if set, ok := b.gtidSet.(*mysql.MariadbGTIDSet); ok {
+			offset.GTIDSet = mariadbSlavePos(set)
+		}
+	}
+	b.committed = offset
+	return offset
+}
 
-			if txID != "" && b.currentTxID == "" {
-				b.currentTxID = txID
+func (b *BinlogInspector) handleEvent(out chan<- model.Event, ev *replication.BinlogEvent) {
+	switch e := ev.Event.(type) {
+	case *replication.PreviousGTIDsEvent:
+		// Every MySQL binlog file starts with the set executed before it,
+		// which seeds GTID tracking when we started from a file position.
+		if b.gtidSet == nil && b.dbType == model.MySQL {
+			if gset, err := mysql.ParseMysqlGTIDSet(e.GTIDSets); err == nil {
+				b.gtidSet = gset
+			}
+		}
+	case *replication.MariadbGTIDListEvent:
+		// MariaDB's counterpart: the binlog state as of the file start
+		if b.gtidSet == nil && b.dbType == model.MariaDB {
+			if gset, err := mariadbGTIDList(e); err == nil {
+				b.gtidSet = gset
+			}
+		}
+	case *replication.GTIDEvent:
+		// anonymous GTID events (gtid_mode=OFF) carry GNO 0
+		var gtid string
+		if e.GNO > 0 {
+			if next, err := e.GTIDNext(); err == nil {
+				gtid = next.String()
 			}
+		}
+		b.startGroup(out, ev.Header, gtid)
+		if e.OriginalCommitTimestamp > 0 {
+			b.txMeta.CommitTime = e.OriginalCommitTime()
+		}
+	case *replication.MariadbGTIDEvent:
+		b.startGroup(out, ev.Header, e.GTID.String())
+		// MariaDB logs no BEGIN; the GTID event opens the transaction
+		// unless it is a standalone statement such as DDL
+		if !e.IsStandalone() {
+			b.beginTx(out, ev.Header)
+		}
+	case *replication.QueryEvent:
+		b.onQuery(out, ev.Header, e)
+	case *replication.RowsQueryEvent:
+		b.statement = b.captureStatement(e.Query)
+	case *replication.MariadbAnnotateRowsEvent:
+		b.statement = b.captureStatement(e.Query)
+	case *replication.TableMapEvent:
+		b.onTableMap(e)
+	case *replication.RowsEvent:
+		b.emitRowEvents(out, ev.Header, e)
+	case *replication.XIDEvent:
+		b.txMeta.XID = e.XID
+		b.endTx(out, ev.Header, model.TxCommit)
+	case *replication.RotateEvent:
+		b.currentFile = string(e.NextLogName)
+	case *replication.GenericEvent:
+		if ev.Header.EventType != replication.XA_PREPARE_LOG_EVENT {
+			log.Printf("[binlog] unhandled event type: %s", ev.Header.EventType)
+			return
+		}
+		// MySQL ends the event group of an XA transaction at XA PREPARE,
+		// and of XA COMMIT ... ONE PHASE with the same event
+		b.endTx(out, ev.Header, model.TxCommit)
+	default:
+		log.Printf("[binlog] unhandled event type: %T", ev.Event)
+	}
+}
+
+func (b *BinlogInspector) onQuery(out chan<- model.Event, h *replication.EventHeader, e *replication.QueryEvent) {
+	query := strings.TrimSpace(string(e.Query))
+	upper := strings.ToUpper(query)
+
+	// transaction control is logged with the session's default schema, so
+	// it is handled before the system schema filter
+	switch {
+	case upper == "BEGIN":
+		b.beginTx(out, h)
+		return
+	case upper == "COMMIT":
+		b.endTx(out, h, model.TxCommit)
+		return
+	case upper == "ROLLBACK":
+		b.endTx(out, h, model.TxRollback)
+		return
+	case strings.HasPrefix(upper, "SAVEPOINT "):
+		b.savepoint(out, h, model.TxSavepoint, savepointName(query))
+		return
+	case strings.HasPrefix(upper, "ROLLBACK TO "):
+		b.savepoint(out, h, model.TxRollbackTo, savepointName(query))
+		return
+	case strings.HasPrefix(upper, "XA "):
+		b.onXA(out, h, query)
+		return
+	}
 
-			switch e := ev.Event.(type) {
-			case *replication.TableMapEvent:
-				b.onTableMap(e)
-			case *replication.RowsEvent:
-				b.emitRowEvents(out, ev.Header, e)
-			case *replication.RotateEvent:
-				b.currentFile = string(e.NextLogName)
-			case *replication.XIDEvent, *replication.GTIDEvent, *replication.QueryEvent:
-				if b.currentTxID != "" {
-					eventTime := time.Unix(int64(ev.Header.Timestamp), 0)
-
-					offset := model.MySQLOffset{
-						File: b.currentFile,
-						Pos:  ev.Header.LogPos,
-					}
-					out <- model.NewTransactionBoundaryEvent(model.SourceType(b.dbType), offset, eventTime, b.currentTxID, model.TxCommit)
-					if err := util.SaveJSON(b.offsetPath, offset); err != nil {
-						log.Printf("[binlog] failed to save offset: %v", err)
-					}
-					b.currentTxID = ""
-				}
-			default:
-				log.Printf("[binlog] unhandled event type: %T", ev.Event)
+	if isSystemSchema(e.Schema) {
+		if !b.inTx {
+			b.endTx(out, h, model.TxCommit)
+		}
+		return
+	}
+
+	if strings.Contains(query, "ALTER TABLE") || strings.Contains(query, "CREATE TABLE") {
+		log.Printf("[schema] DDL detected: %s. Refreshing metadata...", query)
+
+		for key := range b.tableMeta {
+			schema, table := splitKey(key)
+			cols := b.fetchColumns(schema, table)
+
+			if len(cols) > 0 {
+				b.tableMeta[key].columns = cols
+				b.tableMeta[key].engine = ""
+				b.updatePKIndex(key)
 			}
 		}
 	}
+
+	if isDML(e) {
+		b.beginTx(out, h)
+		return
+	}
+
+	// DDL commits implicitly and is logged as its own transaction
+	implicit := !b.inTx
+	if implicit {
+		b.beginTx(out, h)
+	}
+
+	eventTime := time.Unix(int64(h.Timestamp), 0)
+	offset := model.MySQLOffset{
+		File: b.currentFile,
+		Pos:  h.LogPos,
+	}
+
+	// truncating a captured table is a change to its data, not its schema
+	if key, ok := truncateTarget(query, string(e.Schema)); ok && b.tableMeta[key] != nil {
+		schema, table := splitKey(key)
+		meta := b.tableMeta[key]
+		out <- model.NewBinlogRowEvent(model.SourceType(b.dbType), offset, eventTime, b.currentTxID, []model.RowChange{{
+			Schema:    schema,
+			Table:     table,
+			Op:        model.OpTruncate,
+			Columns:   meta.describeColumns(),
+			Statement: b.captureStatement(e.Query),
+		}})
+	} else {
+		out <- model.NewBinlogDDLEvent(model.SourceType(b.dbType), offset, eventTime, b.currentTxID, string(e.Schema), query)
+	}
+
+	if implicit {
+		b.endTx(out, h, model.TxCommit)
+	}
+}
+
+// onXA maps XA statements to transaction boundaries. The binlog logs a
+// prepared XA transaction as an event group of its own, ending at XA PREPARE,
+// so its rows are published as committed there; the XA COMMIT or XA ROLLBACK
+// that decides it later is another, empty group.
+func (b *BinlogInspector) onXA(out chan<- model.Event, h *replication.EventHeader, query string) {
+	words := strings.Fields(strings.ToUpper(query))
+	if len(words) < 2 {
+		return
+	}
+
+	switch words[1] {
+	case "START", "BEGIN":
+		b.beginTx(out, h)
+		b.xa = true
+	case "END":
+		// the transaction stays open until it is prepared or decided
+	case "PREPARE":
+		// MariaDB logs XA PREPARE as a statement
+		b.endTx(out, h, model.TxCommit)
+	case "COMMIT":
+		b.beginTx(out, h)
+		b.endTx(out, h, model.TxCommit)
+	case "ROLLBACK":
+		if !b.xa {
+			msg := fmt.Sprintf("prepared XA transaction rolled back after its rows were published: %s", query)
+			log.Printf("[binlog] %s", msg)
+			out <- model.NewWarningEvent(model.SourceType(b.dbType), model.MySQLOffset{File: b.currentFile, Pos: h.LogPos}, time.Unix(int64(h.Timestamp), 0), model.WarningXARollback, msg)
+		}
+		b.beginTx(out, h)
+		b.endTx(out, h, model.TxRollback)
+	}
 }
 
 func (b *BinlogInspector) onTableMap(e *replication.TableMapEvent) {
@@ -208,6 +589,11 @@ func (b *BinlogInspector) onTableMap(e *replication.TableMapEvent) {
 	} else {
 		meta.columns = bytesToStrings(e.ColumnName)
 	}
+	meta.columnTypes, meta.nullable = columnTypes(e)
+	meta.sqlTypes = sqlTypes(e)
+	if meta.engine == "" {
+		meta.engine = b.fetchEngine(string(e.Schema), string(e.Table))
+	}
 
 	meta.pkIndex = -1
 	for i, col := range meta.columns {
@@ -229,13 +615,14 @@ func (b *BinlogInspector) emitRowEvents(out chan<- model.Event, h *replication.E
 	}
 
 	table := fmt.Sprintf("%s.%s", e.Table.Schema, e.Table.Table)
-	if b.currentTxID == "" && !isSystemSchema(e.Table.Schema) {
-		b.currentTxID = fmt.Sprintf("tx:%d", h.LogPos)
-	}
+	b.beginTx(out, h)
 
 	eventTime := time.Unix(int64(h.Timestamp), 0)
 	meta, ok := b.tableMeta[table]
 	if !ok {
+		if b.removed[table] || len(e.Rows) == 0 {
+			return
+		}
 		log.Printf("[binlog] warning: tableMeta missing for %s, generating default columns", table)
 
 		meta = &tableMeta{
@@ -254,18 +641,21 @@ func (b *BinlogInspector) emitRowEvents(out chan<- model.Event, h *replication.E
 	schema := string(e.Table.Schema)
 	tableName := string(e.Table.Table)
 
-	var op model.OpType
-	switch h.EventType {
-	case replication.WRITE_ROWS_EVENTv2:
-		op = model.OpInsert
-	case replication.DELETE_ROWS_EVENTv2:
-		op = model.OpDelete
-	case replication.UPDATE_ROWS_EVENTv2:
-		op = model.OpUpdate
-	default:
+	op, ok := rowsOp(h.EventType)
+	if !ok {
+		log.Printf("[binlog] unsupported rows event %s table=%s", h.EventType, table)
 		return
 	}
 
+	// SkippedColumns lists, per image, the columns a minimal or noblob row
+	// image did not log; it is empty for full images.
+	skipped := func(i int) []int {
+		if i < len(e.SkippedColumns) {
+			return e.SkippedColumns[i]
+		}
+		return nil
+	}
+
 	var rowsData []model.RowData
 	if op == model.OpUpdate {
 		if len(e.Rows)%2 != 0 {
@@ -273,21 +663,32 @@ func (b *BinlogInspector) emitRowEvents(out chan<- model.Event, h *replication.E
 			return
 		}
 		for i := 0; i < len(e.Rows); i += 2 {
-			before := e.Rows[i]
-			after := e.Rows[i+1]
+			before, _ := imageToMap(meta.columns, e.Rows[i], skipped(i))
+			after, diffs := imageToMap(meta.columns, e.Rows[i+1], skipped(i+1))
+
+			pk := imagePK(meta, e.Rows[i], skipped(i))
+			if pk == nil {
+				pk = imagePK(meta, e.Rows[i+1], skipped(i+1))
+			}
 			rowsData = append(rowsData, model.RowData{
-				PK:     extractPK(meta, before),
-				Before: rowToMap(meta.columns, before),
-				After:  rowToMap(meta.columns, after),
+				PK:      pk,
+				Before:  before,
+				After:   after,
+				Diffs:   diffs,
+				Partial: len(skipped(i)) > 0 || len(skipped(i+1)) > 0 || len(diffs) > 0,
 			})
 		}
 	} else {
-		for _, row := range e.Rows {
-			data := model.RowData{PK: extractPK(meta, row)}
+		for i, row := range e.Rows {
+			image, _ := imageToMap(meta.columns, row, skipped(i))
+			data := model.RowData{
+				PK:      imagePK(meta, row, skipped(i)),
+				Partial: len(skipped(i)) > 0,
+			}
 			if op == model.OpInsert {
-				data.After = rowToMap(meta.columns, row)
+				data.After = image
 			} else {
-				data.Before = rowToMap(meta.columns, row)
+				data.Before = image
 			}
 			rowsData = append(rowsData, data)
 		}
@@ -301,9 +702,14 @@ func (b *BinlogInspector) emitRowEvents(out chan<- model.Event, h *replication.E
 			b.currentTxID,
 			[]model.RowChange{
 				{
-					Schema: schema,
-					Table:  tableName,
-					Op:     op, Rows: rowsData,
+					Schema:  schema,
+					Table:   tableName,
+					Op:      op,
+					Columns: meta.describeColumns(),
+					Rows:    rowsData,
+
+					Statement:        b.statement,
+					NonTransactional: isNonTransactional(meta.engine),
 				},
 			})
 	}
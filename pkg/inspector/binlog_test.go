@@ -1,9 +1,14 @@
 package inspector
 
 import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
 	"testing"
 
+	"github.com/cursus-io/tabellarius/pkg/config"
 	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
 )
 
@@ -23,12 +28,55 @@ func TestParseDSN(t *testing.T) {
 	}
 }
 
+func TestParseDSN_FromConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		db   config.Database
+		host string
+		port uint16
+	}{
+		{"special password", config.Database{Password: "p@ss:w/rd", Host: "db", Port: 3306}, "db", 3306},
+		{"ipv6", config.Database{Password: "pass", Host: "fd00::1", Port: 3307}, "fd00::1", 3307},
+		{"unix socket", config.Database{Password: "pass", Socket: "/run/mysqld/mysqld.sock"}, "/run/mysqld/mysqld.sock", 0},
+		{"tls", config.Database{Password: "pass", Host: "db", Port: 3306, TLS: config.TLS{Mode: config.TLSRequired}}, "db", 3306},
+	}
+
+	for _, tt := range tests {
+		db := tt.db
+		db.Type, db.User, db.Schema = model.MySQL, "user", "mydb"
+		dsn, err := (&config.Config{Database: db}).DSN()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		b := &BinlogInspector{dsn: dsn}
+
+		if err := b.parseDSN(); err != nil {
+			t.Fatalf("%s: parseDSN failed: %v", tt.name, err)
+		}
+		if b.user != "user" || b.password != db.Password.Value() {
+			t.Fatalf("%s: auth parse failed: %s/%s", tt.name, b.user, b.password)
+		}
+		if b.host != tt.host || b.port != tt.port {
+			t.Fatalf("%s: expected %s:%d, got %s:%d", tt.name, tt.host, tt.port, b.host, b.port)
+		}
+		if (b.tls != nil) != (db.TLS.Mode != "") {
+			t.Fatalf("%s: unexpected tls config %v", tt.name, b.tls)
+		}
+	}
+
+	b := &BinlogInspector{dsn: "user:pass@tcp(db:3306)/mydb?tls=unregistered"}
+	if err := b.parseDSN(); err == nil {
+		t.Fatal("expected an unknown tls config to be rejected")
+	}
+}
+
 func TestEmitRowEvents_Write(t *testing.T) {
 	out := make(chan model.Event, 1)
 
 	b := &BinlogInspector{
 		currentFile: "binlog.000001",
 		currentTxID: "tx-1",
+		inTx:        true,
 		tableMeta: map[string]*tableMeta{
 			"test.users": {
 				columns: []string{"id", "name"},
@@ -82,6 +130,7 @@ func TestEmitRowEvents_UpdateInvalid(t *testing.T) {
 
 	b := &BinlogInspector{
 		currentTxID: "tx-1",
+		inTx:        true,
 		tableMeta: map[string]*tableMeta{
 			"test.users": {
 				columns: []string{"id", "name"},
@@ -111,3 +160,549 @@ func TestEmitRowEvents_UpdateInvalid(t *testing.T) {
 		t.Fatalf("expected no events for invalid update")
 	}
 }
+
+func TestEmitRowEvents_Variants(t *testing.T) {
+	diff := &replication.JsonDiff{Op: replication.JsonDiffOperationReplace, Path: "$.a", Value: "2"}
+
+	tests := []struct {
+		name    string
+		typ     replication.EventType
+		rows    [][]interface{}
+		skipped [][]int
+		want    string
+	}{
+		{
+			name: "v1 write",
+			typ:  replication.WRITE_ROWS_EVENTv1,
+			rows: [][]interface{}{{1, "alice", nil}},
+			want: "INSERT pk=map[id:1] before=map[] after=map[doc:<nil> id:1 name:alice] diffs=map[] partial=false",
+		},
+		{
+			name: "compressed delete",
+			typ:  replication.MARIADB_DELETE_ROWS_COMPRESSED_EVENT_V1,
+			rows: [][]interface{}{{1, "alice", nil}},
+			want: "DELETE pk=map[id:1] before=map[doc:<nil> id:1 name:alice] after=map[] diffs=map[] partial=false",
+		},
+		{
+			name:    "minimal update",
+			typ:     replication.UPDATE_ROWS_EVENTv1,
+			rows:    [][]interface{}{{1, nil, nil}, {nil, nil, nil}},
+			skipped: [][]int{{1, 2}, {0, 2}},
+			want:    "UPDATE pk=map[id:1] before=map[id:1] after=map[name:<nil>] diffs=map[] partial=true",
+		},
+		{
+			name:    "partial json update",
+			typ:     replication.PARTIAL_UPDATE_ROWS_EVENT,
+			rows:    [][]interface{}{{1, nil, nil}, {nil, nil, diff}},
+			skipped: [][]int{{1, 2}, {0, 1}},
+			want:    "UPDATE pk=map[id:1] before=map[id:1] after=map[] diffs=map[doc:{replace $.a 2}] partial=true",
+		},
+	}
+
+	for _, tt := range tests {
+		out := make(chan model.Event, 1)
+		b := &BinlogInspector{
+			currentTxID: "tx-1",
+			inTx:        true,
+			tableMeta: map[string]*tableMeta{
+				"test.users": {pkName: "id", columns: []string{"id", "name", "doc"}},
+			},
+		}
+
+		ev := &replication.RowsEvent{
+			Table:          &replication.TableMapEvent{Schema: []byte("test"), Table: []byte("users")},
+			Rows:           tt.rows,
+			SkippedColumns: tt.skipped,
+		}
+		b.emitRowEvents(out, &replication.EventHeader{EventType: tt.typ, LogPos: 10}, ev)
+		close(out)
+
+		got, ok := <-out
+		if !ok {
+			t.Fatalf("%s: no event emitted", tt.name)
+		}
+		change := got.(*model.BinlogRowEvent).Changes()[0]
+		row := change.Rows[0]
+		desc := fmt.Sprintf("%s pk=%v before=%v after=%v diffs=%v partial=%v", change.Op, row.PK, row.Before, row.After, row.Diffs, row.Partial)
+		if desc != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, desc)
+		}
+	}
+}
+
+func TestOnTableMap_ColumnTypes(t *testing.T) {
+	b := &BinlogInspector{
+		tableMeta: map[string]*tableMeta{
+			"test.users": NewTableMeta("id"),
+		},
+	}
+
+	b.onTableMap(&replication.TableMapEvent{
+		Schema:     []byte("test"),
+		Table:      []byte("users"),
+		ColumnName: [][]byte{[]byte("id"), []byte("name")},
+		ColumnType: []byte{mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_VARCHAR},
+		ColumnMeta: []uint16{0, 255},
+		NullBitmap: []byte{0x02},
+	})
+
+	cols := b.tableMeta["test.users"].describeColumns()
+	if len(cols) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(cols))
+	}
+	if cols[0].Type != "bigint" || cols[0].Nullable {
+		t.Fatalf("unexpected id column: %+v", cols[0])
+	}
+	if cols[1].Type != "varchar" || !cols[1].Nullable {
+		t.Fatalf("unexpected name column: %+v", cols[1])
+	}
+}
+
+func TestSQLTypes(t *testing.T) {
+	e := &replication.TableMapEvent{
+		ColumnCount: 10,
+		ColumnType: []byte{
+			mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_NEWDECIMAL,
+			mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_STRING,
+			mysql.MYSQL_TYPE_STRING, mysql.MYSQL_TYPE_DATETIME2, mysql.MYSQL_TYPE_BLOB, mysql.MYSQL_TYPE_BLOB,
+		},
+		ColumnMeta: []uint16{
+			0, 0, 10<<8 | 2,
+			1020, 16, uint16(mysql.MYSQL_TYPE_STRING)<<8 | 30,
+			uint16(mysql.MYSQL_TYPE_ENUM)<<8 | 1, 3, 2, 4,
+		},
+		NullBitmap: []byte{0, 0},
+		// only the bigint is unsigned
+		SignednessBitmap: []byte{0x40},
+		// utf8mb4 by default; varbinary, utf8 char, utf8mb4 text and blob
+		DefaultCharset: []uint64{255, 1, 63, 2, 33, 3, 45, 4, 63},
+		EnumStrValue:   [][][]byte{{[]byte("a"), []byte("it's")}},
+	}
+
+	want := []string{
+		"int(11)", "bigint(20) unsigned", "decimal(10,2)",
+		"varchar(255)", "varbinary(16)", "char(10)",
+		"enum('a','it''s')", "datetime(3)", "text", "longblob",
+	}
+	if got := sqlTypes(e); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	// without binlog_row_metadata=FULL only the lengths in the table map
+	// are known
+	minimal := &replication.TableMapEvent{
+		ColumnCount: 3,
+		ColumnType:  []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_NEWDECIMAL},
+		ColumnMeta:  []uint16{0, 1020, 10<<8 | 2},
+		NullBitmap:  []byte{0},
+	}
+	want = []string{"int(11)", "varchar", "decimal(10,2)"}
+	if got := sqlTypes(minimal); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+// recorded builds a binlog event as delivered by the syncer.
+func recorded(pos uint32, typ replication.EventType, e replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: typ, LogPos: pos, Timestamp: 1700000000},
+		Event:  e,
+	}
+}
+
+func query(pos uint32, q string) *replication.BinlogEvent {
+	return recorded(pos, replication.QUERY_EVENT, &replication.QueryEvent{Schema: []byte("shop"), Query: []byte(q)})
+}
+
+func insert(pos uint32, table string, id int) *replication.BinlogEvent {
+	return recorded(pos, replication.WRITE_ROWS_EVENTv2, &replication.RowsEvent{
+		Table: &replication.TableMapEvent{Schema: []byte("shop"), Table: []byte(table)},
+		Rows:  [][]interface{}{{id}},
+	})
+}
+
+func xid(pos uint32) *replication.BinlogEvent {
+	return recorded(pos, replication.XID_EVENT, &replication.XIDEvent{XID: uint64(pos)})
+}
+
+func gtid(pos uint32, gno int64) *replication.BinlogEvent {
+	sid := []byte{0x3e, 0x11, 0xfa, 0x47, 0x71, 0xca, 0x11, 0xe1, 0x9e, 0x33, 0xc8, 0x0a, 0xa9, 0x42, 0x95, 0x62}
+	return recorded(pos, replication.GTID_EVENT, &replication.GTIDEvent{SID: sid, GNO: gno})
+}
+
+// replay feeds events through the inspector and describes what it emitted,
+// e.g. "BEGIN", "rows:orders", "ddl", "COMMIT".
+func replay(t *testing.T, events ...*replication.BinlogEvent) ([]string, []model.Event) {
+	t.Helper()
+	kinds, emitted, _ := replayAs(t, model.MySQL, events...)
+	return kinds, emitted
+}
+
+// replayAs is replay against a dbType server, also returning the inspector.
+func replayAs(t *testing.T, dbType model.DatabaseType, events ...*replication.BinlogEvent) ([]string, []model.Event, *BinlogInspector) {
+	t.Helper()
+
+	b := &BinlogInspector{
+		dbType:      dbType,
+		currentFile: "binlog.000001",
+		opts:        Options{Statements: config.Statements{Capture: true}},
+		tableMeta: map[string]*tableMeta{
+			"shop.orders": {pkName: "id", columns: []string{"id"}, engine: "InnoDB"},
+			"shop.audit":  {pkName: "id", columns: []string{"id"}, engine: "MyISAM"},
+		},
+	}
+
+	out := make(chan model.Event, 64)
+	for _, ev := range events {
+		b.handleEvent(out, ev)
+	}
+	close(out)
+
+	var kinds []string
+	var emitted []model.Event
+	for evt := range out {
+		emitted = append(emitted, evt)
+		switch e := evt.(type) {
+		case *model.TransactionBoundaryEvent:
+			kinds = append(kinds, string(e.Kind()))
+		case *model.BinlogRowEvent:
+			kinds = append(kinds, "rows:"+e.Changes()[0].Table)
+		case *model.BinlogDDLEvent:
+			kinds = append(kinds, "ddl")
+		}
+	}
+	return kinds, emitted, b
+}
+
+func TestHandleEvent_Boundaries(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*replication.BinlogEvent
+		want   []string
+	}{
+		{
+			name:   "xid commit",
+			events: []*replication.BinlogEvent{gtid(100, 7), query(150, "BEGIN"), insert(200, "orders", 1), xid(250)},
+			want:   []string{"BEGIN", "rows:orders", "COMMIT"},
+		},
+		{
+			name:   "query commit for non-transactional engine",
+			events: []*replication.BinlogEvent{query(150, "BEGIN"), insert(200, "audit", 1), query(250, "COMMIT")},
+			want:   []string{"BEGIN", "rows:audit", "COMMIT"},
+		},
+		{
+			name:   "ddl is its own transaction",
+			events: []*replication.BinlogEvent{gtid(100, 8), query(200, "DROP TABLE old_orders")},
+			want:   []string{"BEGIN", "ddl", "COMMIT"},
+		},
+		{
+			name:   "truncate of a captured table",
+			events: []*replication.BinlogEvent{gtid(100, 8), query(200, "TRUNCATE TABLE `shop`.`orders`")},
+			want:   []string{"BEGIN", "rows:orders", "COMMIT"},
+		},
+		{
+			name:   "truncate of another table",
+			events: []*replication.BinlogEvent{gtid(100, 8), query(200, "TRUNCATE scratch")},
+			want:   []string{"BEGIN", "ddl", "COMMIT"},
+		},
+		{
+			name: "rollback to savepoint",
+			events: []*replication.BinlogEvent{
+				query(150, "BEGIN"),
+				insert(200, "orders", 1),
+				query(250, "SAVEPOINT `sp1`"),
+				insert(300, "orders", 2),
+				insert(350, "audit", 2),
+				query(400, "ROLLBACK TO `sp1`"),
+				xid(450),
+			},
+			want: []string{"BEGIN", "rows:orders", "SAVEPOINT", "rows:orders", "rows:audit", "ROLLBACK TO", "COMMIT"},
+		},
+		{
+			name:   "mixed engine rollback",
+			events: []*replication.BinlogEvent{query(150, "BEGIN"), insert(200, "orders", 1), insert(250, "audit", 1), query(300, "ROLLBACK")},
+			want:   []string{"BEGIN", "rows:orders", "rows:audit", "ROLLBACK"},
+		},
+		{
+			name:   "truncated transaction",
+			events: []*replication.BinlogEvent{gtid(100, 9), query(150, "BEGIN"), insert(200, "orders", 1), gtid(300, 10), query(350, "BEGIN"), xid(400)},
+			want:   []string{"BEGIN", "rows:orders", "ABORT", "BEGIN", "COMMIT"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, _ := replay(t, tt.events...)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestHandleEvent_SharedTxID(t *testing.T) {
+	_, events := replay(t, query(150, "BEGIN"), insert(200, "orders", 1), query(250, "SAVEPOINT sp1"), insert(300, "audit", 2), xid(350))
+
+	txID := ""
+	for _, evt := range events {
+		var id string
+		switch e := evt.(type) {
+		case *model.TransactionBoundaryEvent:
+			id = e.TxID()
+			if e.Kind() == model.TxSavepoint && e.Savepoint() != "sp1" {
+				t.Fatalf("unexpected savepoint name: %q", e.Savepoint())
+			}
+		case *model.BinlogRowEvent:
+			id = e.TxID()
+			if e.Changes()[0].Table == "audit" && !e.Changes()[0].NonTransactional {
+				t.Fatalf("expected audit changes to be non-transactional")
+			}
+		}
+		if txID == "" {
+			txID = id
+		}
+		if id != txID {
+			t.Fatalf("expected all events in %s, got %s", txID, id)
+		}
+	}
+
+	commit := events[len(events)-1].(*model.TransactionBoundaryEvent)
+	if off := commit.Offset().(model.MySQLOffset); off.Pos != 350 {
+		t.Fatalf("expected commit offset 350, got %v", off)
+	}
+}
+
+// TestHandleEvent_BeginOffset checks that BEGIN carries the previous commit,
+// the point to resume from when a transaction is cut short.
+func TestHandleEvent_BeginOffset(t *testing.T) {
+	first := gtid(100, 7)
+	first.Header.EventSize = 20
+	_, events := replay(t, first, query(150, "BEGIN"), insert(200, "orders", 1), xid(250),
+		gtid(300, 8), query(350, "BEGIN"), insert(400, "orders", 2), xid(450))
+
+	var begins, commits []model.MySQLOffset
+	for _, evt := range events {
+		e, ok := evt.(*model.TransactionBoundaryEvent)
+		if !ok {
+			continue
+		}
+		switch e.Kind() {
+		case model.TxBegin:
+			begins = append(begins, e.Offset().(model.MySQLOffset))
+		case model.TxCommit:
+			commits = append(commits, e.Offset().(model.MySQLOffset))
+		}
+	}
+	if len(begins) != 2 || len(commits) != 2 {
+		t.Fatalf("expected two transactions, got %v and %v", begins, commits)
+	}
+	if want := (model.MySQLOffset{File: "binlog.000001", Pos: 80}); begins[0] != want {
+		t.Fatalf("expected first BEGIN at its group start %v, got %v", want, begins[0])
+	}
+	if begins[1] != commits[0] {
+		t.Fatalf("expected second BEGIN at the previous commit %v, got %v", commits[0], begins[1])
+	}
+}
+
+func TestHandleEvent_TxIdentity(t *testing.T) {
+	withServer := func(ev *replication.BinlogEvent) *replication.BinlogEvent {
+		ev.Header.ServerID = 7
+		ev.Header.EventSize = 20
+		return ev
+	}
+	mariadb := recorded(100, replication.MARIADB_GTID_EVENT, &replication.MariadbGTIDEvent{
+		GTID: mysql.MariadbGTID{DomainID: 0, ServerID: 1, SequenceNumber: 42},
+	})
+
+	tests := []struct {
+		name   string
+		events []*replication.BinlogEvent
+		want   string
+	}{
+		{
+			name:   "mysql gtid",
+			events: []*replication.BinlogEvent{gtid(100, 7), query(150, "BEGIN"), insert(200, "orders", 1), xid(250)},
+			want:   "3e11fa47-71ca-11e1-9e33-c80aa9429562:7",
+		},
+		{
+			name:   "mariadb gtid",
+			events: []*replication.BinlogEvent{mariadb, insert(200, "orders", 1), xid(250)},
+			want:   "0-1-42",
+		},
+		{
+			name:   "anonymous gtid",
+			events: []*replication.BinlogEvent{withServer(gtid(100, 0)), query(150, "BEGIN"), insert(200, "orders", 1), xid(250)},
+			want:   "binlog.000001:80",
+		},
+		{
+			name:   "no gtid",
+			events: []*replication.BinlogEvent{withServer(query(150, "BEGIN")), insert(200, "orders", 1), xid(250)},
+			want:   "binlog.000001:130",
+		},
+	}
+
+	for _, tt := range tests {
+		_, events := replay(t, tt.events...)
+		if len(events) != 3 {
+			t.Fatalf("%s: expected BEGIN, rows and COMMIT, got %d events", tt.name, len(events))
+		}
+		for _, evt := range events {
+			if id := evt.(interface{ TxID() string }).TxID(); id != tt.want {
+				t.Fatalf("%s: expected txID %s, got %s (%T)", tt.name, tt.want, id, evt)
+			}
+		}
+	}
+}
+
+func TestHandleEvent_CommitMeta(t *testing.T) {
+	g := gtid(100, 7)
+	g.Header.ServerID = 3
+	g.Event.(*replication.GTIDEvent).OriginalCommitTimestamp = 1700000000123456
+
+	_, events := replay(t, g, query(150, "BEGIN"), insert(200, "orders", 1), xid(250))
+
+	commit := events[len(events)-1].(*model.TransactionBoundaryEvent)
+	meta := commit.Meta()
+	if meta.GTID != "3e11fa47-71ca-11e1-9e33-c80aa9429562:7" || meta.XID != 250 || meta.ServerID != 3 {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	if meta.CommitTime.UnixMicro() != 1700000000123456 {
+		t.Fatalf("expected original commit time, got %v", meta.CommitTime)
+	}
+}
+
+// mysqlParser returns a parser that has read the format description of a
+// MySQL 8.0 binlog, without event checksums.
+func mysqlParser(t *testing.T) *replication.BinlogParser {
+	t.Helper()
+	p := replication.NewBinlogParser()
+	p.SetFlavor(mysql.MySQLFlavor)
+
+	fde := make([]byte, 2+50+4+1)
+	binary.LittleEndian.PutUint16(fde, 4)
+	copy(fde[2:], "8.0.36-log")
+	fde[56] = replication.EventHeaderSize
+	headerLengths := make([]byte, replication.XA_PREPARE_LOG_EVENT)
+	headerLengths[replication.QUERY_EVENT-1] = 13
+	headerLengths[replication.TABLE_MAP_EVENT-1] = 8
+	headerLengths[replication.WRITE_ROWS_EVENTv2-1] = 10
+	fde = append(append(fde, headerLengths...), replication.BINLOG_CHECKSUM_ALG_OFF, 0, 0, 0, 0)
+	parse(t, p, 4, replication.FORMAT_DESCRIPTION_EVENT, fde)
+	return p
+}
+
+func parsedQuery(t *testing.T, p *replication.BinlogParser, pos uint32, q string) *replication.BinlogEvent {
+	body := make([]byte, 13)
+	body[8] = byte(len("shop"))
+	body = append(append(body, "shop"...), 0)
+	return parse(t, p, pos, replication.QUERY_EVENT, append(body, q...))
+}
+
+// parsedInsert writes id into table id 1 as a v2 rows event.
+func parsedInsert(t *testing.T, p *replication.BinlogParser, pos uint32, id int32) *replication.BinlogEvent {
+	body := []byte{1, 0, 0, 0, 0, 0}
+	body = binary.LittleEndian.AppendUint16(body, replication.RowsEventStmtEndFlag)
+	body = binary.LittleEndian.AppendUint16(body, 2)
+	body = append(body, 1, 0x01, 0)
+	return parse(t, p, pos, replication.WRITE_ROWS_EVENTv2, binary.LittleEndian.AppendUint32(body, uint32(id)))
+}
+
+// xaPrepare is the XA_PREPARE_LOG_EVENT of xid X'78',X”,1.
+func xaPrepare(t *testing.T, p *replication.BinlogParser, pos uint32, onePhase bool) *replication.BinlogEvent {
+	body := []byte{0}
+	if onePhase {
+		body[0] = 1
+	}
+	body = binary.LittleEndian.AppendUint32(body, 1)
+	body = binary.LittleEndian.AppendUint32(body, 1)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	return parse(t, p, pos, replication.XA_PREPARE_LOG_EVENT, append(body, 'x'))
+}
+
+func TestHandleEvent_XA(t *testing.T) {
+	p := mysqlParser(t)
+	prepared := func(pos uint32, gno int64, table string, onePhase bool) []*replication.BinlogEvent {
+		return []*replication.BinlogEvent{
+			gtid(pos, gno),
+			parsedQuery(t, p, pos+10, "XA START X'78',X'',1"),
+			mapTable(t, p, pos+20, table),
+			parsedInsert(t, p, pos+30, 1),
+			parsedQuery(t, p, pos+40, "XA END X'78',X'',1"),
+			xaPrepare(t, p, pos+50, onePhase),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		dbType   model.DatabaseType
+		events   []*replication.BinlogEvent
+		want     []string
+		warnings int
+	}{
+		{
+			name:   "one phase",
+			dbType: model.MySQL,
+			events: append(prepared(100, 1, "orders", true), gtid(200, 2), parsedQuery(t, p, 210, "BEGIN"), mapTable(t, p, 215, "orders"), parsedInsert(t, p, 220, 2), xid(230)),
+			want:   []string{"BEGIN", "rows:orders", "COMMIT", "BEGIN", "rows:orders", "COMMIT"},
+		},
+		{
+			name:   "two phase commit",
+			dbType: model.MySQL,
+			events: append(prepared(100, 1, "orders", false), gtid(200, 2), parsedQuery(t, p, 210, "XA COMMIT X'78',X'',1")),
+			want:   []string{"BEGIN", "rows:orders", "COMMIT", "BEGIN", "COMMIT"},
+		},
+		{
+			name:     "two phase rollback",
+			dbType:   model.MySQL,
+			events:   append(prepared(100, 1, "orders", false), gtid(200, 2), parsedQuery(t, p, 210, "XA ROLLBACK X'78',X'',1")),
+			want:     []string{"BEGIN", "rows:orders", "COMMIT", "BEGIN", "ROLLBACK"},
+			warnings: 1,
+		},
+		{
+			name:   "rollback before prepare",
+			dbType: model.MySQL,
+			events: []*replication.BinlogEvent{
+				gtid(100, 1),
+				parsedQuery(t, p, 110, "XA START X'78',X'',1"),
+				mapTable(t, p, 120, "audit"),
+				parsedInsert(t, p, 130, 1),
+				parsedQuery(t, p, 140, "XA END X'78',X'',1"),
+				parsedQuery(t, p, 150, "XA ROLLBACK X'78',X'',1"),
+			},
+			want: []string{"BEGIN", "rows:audit", "ROLLBACK"},
+		},
+		{
+			name:   "mariadb two phase commit",
+			dbType: model.MariaDB,
+			events: []*replication.BinlogEvent{
+				mariadbGTID(100, 0, 1, 1, false),
+				parsedQuery(t, p, 110, "XA START X'78',X'',1"),
+				mapTable(t, p, 120, "orders"),
+				parsedInsert(t, p, 130, 1),
+				parsedQuery(t, p, 140, "XA END X'78',X'',1"),
+				parsedQuery(t, p, 150, "XA PREPARE X'78',X'',1"),
+				mariadbGTID(200, 0, 1, 2, true),
+				parsedQuery(t, p, 210, "XA COMMIT X'78',X'',1"),
+			},
+			want: []string{"BEGIN", "rows:orders", "COMMIT", "BEGIN", "COMMIT"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, emitted, _ := replayAs(t, tt.dbType, tt.events...)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+
+		warnings := 0
+		for _, evt := range emitted {
+			if w, ok := evt.(*model.WarningEvent); ok && w.Code() == model.WarningXARollback {
+				warnings++
+			}
+			if e, ok := evt.(*model.BinlogRowEvent); ok && e.Changes()[0].Rows[0].After["id"] == nil {
+				t.Fatalf("%s: row not decoded: %v", tt.name, e.Changes()[0].Rows[0])
+			}
+		}
+		if warnings != tt.warnings {
+			t.Fatalf("%s: expected %d xa_rollback warnings, got %d", tt.name, tt.warnings, warnings)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package inspector
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// writeBinlog writes a binlog file of a format description event followed by
+// a query event per statement, returning the position of each statement.
+func writeBinlog(t *testing.T, path string, queries ...string) []uint32 {
+	t.Helper()
+
+	data := []byte{0xfe, 'b', 'i', 'n'}
+	add := func(typ replication.EventType, body []byte) uint32 {
+		start := uint32(len(data))
+		h := make([]byte, replication.EventHeaderSize)
+		binary.LittleEndian.PutUint32(h[0:], 1_700_000_000)
+		h[4] = byte(typ)
+		binary.LittleEndian.PutUint32(h[5:], 1)
+		binary.LittleEndian.PutUint32(h[9:], uint32(len(h)+len(body)))
+		binary.LittleEndian.PutUint32(h[13:], start+uint32(len(h)+len(body)))
+		data = append(append(data, h...), body...)
+		return start
+	}
+
+	// a pre-5.6 server version, so events carry no checksum
+	fde := make([]byte, 2+50+4+1)
+	binary.LittleEndian.PutUint16(fde, 4)
+	copy(fde[2:], "5.5.0")
+	fde[56] = replication.EventHeaderSize
+	headerLengths := make([]byte, 40)
+	headerLengths[replication.QUERY_EVENT-1] = 13
+	add(replication.FORMAT_DESCRIPTION_EVENT, append(fde, headerLengths...))
+
+	var positions []uint32
+	for _, q := range queries {
+		body := make([]byte, 13)
+		body[8] = byte(len("shop"))
+		body = append(append(body, "shop"...), 0)
+		positions = append(positions, add(replication.QUERY_EVENT, append(body, q...)))
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return positions
+}
+
+func TestBinlogFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"bin.000002", "bin.000001", "bin.index", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := BinlogFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "bin.000001"), filepath.Join(dir, "bin.000002")}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+
+	if files, err := BinlogFiles(want[1]); err != nil || !reflect.DeepEqual(files, want[1:]) {
+		t.Fatalf("expected the file itself, got %v, %v", files, err)
+	}
+	if _, err := BinlogFiles(t.TempDir()); err == nil {
+		t.Fatal("expected an error for a directory without binlogs")
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := writeBinlog(t, filepath.Join(dir, "bin.000001"), "CREATE TABLE a (id int)", "CREATE TABLE b (id int)")
+	second := writeBinlog(t, filepath.Join(dir, "bin.000002"), "CREATE TABLE c (id int)")
+	files, err := BinlogFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts FileOptions
+		want []string
+	}{
+		{"all", FileOptions{}, []string{
+			"bin.000001 CREATE TABLE a (id int)", "bin.000001 CREATE TABLE b (id int)", "bin.000002 CREATE TABLE c (id int)",
+		}},
+		{"from a position", FileOptions{Start: model.MySQLOffset{File: "bin.000001", Pos: first[1]}}, []string{
+			"bin.000001 CREATE TABLE b (id int)", "bin.000002 CREATE TABLE c (id int)",
+		}},
+		{"from a later file", FileOptions{Start: model.MySQLOffset{File: "bin.000002", Pos: 4}}, []string{
+			"bin.000002 CREATE TABLE c (id int)",
+		}},
+		{"to a position", FileOptions{Stop: model.MySQLOffset{File: "bin.000001", Pos: first[1]}}, []string{
+			"bin.000001 CREATE TABLE a (id int)",
+		}},
+		{"to the end of a file", FileOptions{Stop: model.MySQLOffset{File: "bin.000001"}}, []string{
+			"bin.000001 CREATE TABLE a (id int)", "bin.000001 CREATE TABLE b (id int)",
+		}},
+		{"between", FileOptions{
+			Start: model.MySQLOffset{File: "bin.000001", Pos: first[1]},
+			Stop:  model.MySQLOffset{File: "bin.000002", Pos: second[0]},
+		}, []string{
+			"bin.000001 CREATE TABLE b (id int)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &BinlogInspector{dbType: model.MySQL, tableMeta: map[string]*tableMeta{}}
+			out := make(chan model.Event, 64)
+			if err := b.ReadFiles(files, tt.opts).Start(context.Background(), out); err != nil {
+				t.Fatal(err)
+			}
+			close(out)
+
+			var got []string
+			for evt := range out {
+				if ddl, ok := evt.(*model.BinlogDDLEvent); ok {
+					got = append(got, ddl.Offset().(model.MySQLOffset).File+" "+ddl.Query())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
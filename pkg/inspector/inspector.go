@@ -2,16 +2,47 @@ package inspector
 
 import (
 	"context"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
+	"github.com/cursus-io/tabellarius/pkg/model"
 )
 
 type Inspector[T any] interface {
 	Start(ctx context.Context, out chan<- T) error
 }
 
+// Options tunes a BinlogInspector beyond what it needs to connect.
+type Options struct {
+	// OnPurged is one of the config.OnPurged* policies; empty means fail.
+	OnPurged string
+
+	// Statements controls whether row changes carry their SQL statement.
+	Statements config.Statements
+
+	// Metrics receives the inspector's metrics; nil means metrics.Default.
+	Metrics *metrics.Registry
+
+	// Start, when set, is where the stream starts instead of the persisted
+	// offset.
+	Start model.MySQLOffset
+
+	// Schema, keyed by "schema.table", describes tables in place of
+	// information_schema, e.g. when decoding binlog files offline.
+	Schema map[string]TableSchema
+}
+
 type tableMeta struct {
 	pkName  string
 	pkIndex int
 	columns []string
+
+	columnTypes []string
+	sqlTypes    []string
+	nullable    []bool
+
+	// engine is the storage engine, looked up on first use.
+	engine string
 }
 
 func NewTableMeta(pk string) *tableMeta {
@@ -0,0 +1,38 @@
+package inspector
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// mariadbGTIDList returns the binlog state a MariaDB binlog file starts with.
+func mariadbGTIDList(e *replication.MariadbGTIDListEvent) (mysql.GTIDSet, error) {
+	gtids := make([]string, 0, len(e.GTIDs))
+	for _, g := range e.GTIDs {
+		gtids = append(gtids, g.String())
+	}
+	return mysql.ParseMariadbGTIDSet(strings.Join(gtids, ","))
+}
+
+// mariadbSlavePos renders a MariaDB GTID set as a replication position: the
+// last GTID of each domain. The binlog state keeps one GTID per domain and
+// server, but gtid_slave_pos accepts only one per domain.
+func mariadbSlavePos(set *mysql.MariadbGTIDSet) string {
+	var pos []string
+	for _, servers := range set.Sets {
+		var last *mysql.MariadbGTID
+		for _, g := range servers {
+			if last == nil || g.SequenceNumber > last.SequenceNumber {
+				last = g
+			}
+		}
+		if last != nil {
+			pos = append(pos, last.String())
+		}
+	}
+	sort.Strings(pos)
+	return strings.Join(pos, ",")
+}
@@ -0,0 +1,141 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// scanIdleTimeout bounds the wait for the next event while scanning binlogs,
+// which only happens when the scan misses the end of the log.
+const scanIdleTimeout = 30 * time.Second
+
+// CurrentOffset returns the server's current binlog position, where a
+// pipeline resuming from it captures only changes made from now on.
+func (b *BinlogInspector) CurrentOffset(ctx context.Context) (model.MySQLOffset, error) {
+	pos, err := currentPosition(ctx, b.db)
+	if err != nil {
+		return model.MySQLOffset{}, fmt.Errorf("read binlog position: %w", err)
+	}
+	return model.MySQLOffset{File: pos.Name, Pos: pos.Pos}, nil
+}
+
+// FindOffset returns the start of the first transaction logged at or after t,
+// or the current position when there is none. It reads event headers over a
+// replication connection, as SHOW BINLOG EVENTS has no timestamps.
+func (b *BinlogInspector) FindOffset(ctx context.Context, t time.Time) (model.MySQLOffset, error) {
+	end, err := b.CurrentOffset(ctx)
+	if err != nil {
+		return model.MySQLOffset{}, err
+	}
+	files, err := binaryLogs(ctx, b.db)
+	if err != nil {
+		return model.MySQLOffset{}, fmt.Errorf("list binlogs: %w", err)
+	}
+
+	file, err := pickFile(files, t, func(file string) (time.Time, error) {
+		return b.fileStart(ctx, file)
+	})
+	if err != nil {
+		return model.MySQLOffset{}, err
+	}
+
+	syncer := replication.NewBinlogSyncer(b.syncerConfig())
+	defer syncer.Close()
+	streamer, err := syncer.StartSync(mysql.Position{Name: file, Pos: 4})
+	if err != nil {
+		return model.MySQLOffset{}, err
+	}
+	return scanFor(func() (*replication.BinlogEvent, error) {
+		return nextEvent(ctx, streamer)
+	}, file, t, end)
+}
+
+// fileStart returns when file was created, from its format description event.
+func (b *BinlogInspector) fileStart(ctx context.Context, file string) (time.Time, error) {
+	syncer := replication.NewBinlogSyncer(b.syncerConfig())
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(mysql.Position{Name: file, Pos: 4})
+	if err != nil {
+		return time.Time{}, err
+	}
+	for {
+		ev, err := nextEvent(ctx, streamer)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("read %s: %w", file, err)
+		}
+		if _, ok := ev.Event.(*replication.FormatDescriptionEvent); ok {
+			return time.Unix(int64(ev.Header.Timestamp), 0), nil
+		}
+	}
+}
+
+func nextEvent(ctx context.Context, streamer *replication.BinlogStreamer) (*replication.BinlogEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanIdleTimeout)
+	defer cancel()
+	return streamer.GetEvent(ctx)
+}
+
+// pickFile returns the last of files created at or before t, searching with
+// startOf as binlogs are created in order.
+func pickFile(files []string, t time.Time, startOf func(file string) (time.Time, error)) (string, error) {
+	if len(files) == 0 {
+		return "", errors.New("binary logging is disabled on the server")
+	}
+
+	var err error
+	// the first file created after t; the one before it holds t
+	i := sort.Search(len(files), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		start, e := startOf(files[i])
+		if e != nil {
+			err = e
+			return true
+		}
+		return start.After(t)
+	})
+	if err != nil {
+		return "", err
+	}
+	if i == 0 {
+		return "", fmt.Errorf("%s is before the oldest binlog %s; earlier changes were purged", t.Format(time.RFC3339), files[0])
+	}
+	return files[i-1], nil
+}
+
+// scanFor reads events from the start of file until a transaction starting at
+// or after t, stopping at end. Servers open every transaction with a GTID
+// event, anonymous when gtid_mode is off, since MySQL 5.7 and MariaDB 10.0.
+func scanFor(next func() (*replication.BinlogEvent, error), file string, t time.Time, end model.MySQLOffset) (model.MySQLOffset, error) {
+	for {
+		ev, err := next()
+		if err != nil {
+			return model.MySQLOffset{}, fmt.Errorf("scan %s: %w", file, err)
+		}
+		h := ev.Header
+
+		switch e := ev.Event.(type) {
+		case *replication.RotateEvent:
+			file = string(e.NextLogName)
+			continue
+		case *replication.GTIDEvent, *replication.MariadbGTIDEvent:
+			if !time.Unix(int64(h.Timestamp), 0).Before(t) {
+				return model.MySQLOffset{File: file, Pos: h.LogPos - h.EventSize}, nil
+			}
+		}
+
+		if (model.MySQLOffset{File: file, Pos: h.LogPos}).Compare(end) >= 0 {
+			return end, nil
+		}
+	}
+}
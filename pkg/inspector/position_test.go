@@ -0,0 +1,124 @@
+package inspector
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/util"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestPickFile(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	files := []string{"bin.000001", "bin.000002", "bin.000003"}
+	starts := map[string]time.Time{
+		"bin.000001": base,
+		"bin.000002": base.Add(time.Hour),
+		"bin.000003": base.Add(2 * time.Hour),
+	}
+	startOf := func(file string) (time.Time, error) { return starts[file], nil }
+
+	tests := []struct {
+		at      time.Time
+		want    string
+		wantErr bool
+	}{
+		{base.Add(-time.Minute), "", true},
+		{base, "bin.000001", false},
+		{base.Add(30 * time.Minute), "bin.000001", false},
+		{base.Add(time.Hour), "bin.000002", false},
+		{base.Add(5 * time.Hour), "bin.000003", false},
+	}
+	for _, tt := range tests {
+		got, err := pickFile(files, tt.at, startOf)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: unexpected error %v", tt.at, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%v: expected %q, got %q", tt.at, tt.want, got)
+		}
+	}
+
+	failing := func(string) (time.Time, error) { return time.Time{}, errors.New("denied") }
+	if _, err := pickFile(files, base, failing); err == nil {
+		t.Fatal("expected the startOf error")
+	}
+	if _, err := pickFile(nil, base, startOf); err == nil {
+		t.Fatal("expected an error without binlogs")
+	}
+}
+
+func event(ts, pos, size uint32, e replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{
+		Header: &replication.EventHeader{Timestamp: ts, LogPos: pos, EventSize: size},
+		Event:  e,
+	}
+}
+
+func TestScanFor(t *testing.T) {
+	events := []*replication.BinlogEvent{
+		event(0, 0, 44, &replication.RotateEvent{NextLogName: []byte("bin.000001")}),
+		event(100, 126, 122, &replication.FormatDescriptionEvent{}),
+		event(100, 200, 74, &replication.GTIDEvent{}),
+		event(100, 300, 100, &replication.XIDEvent{}),
+		event(150, 350, 50, &replication.RotateEvent{NextLogName: []byte("bin.000002")}),
+		event(150, 126, 122, &replication.FormatDescriptionEvent{}),
+		event(200, 200, 74, &replication.GTIDEvent{}),
+		event(200, 300, 100, &replication.XIDEvent{}),
+	}
+	source := func() func() (*replication.BinlogEvent, error) {
+		i := 0
+		return func() (*replication.BinlogEvent, error) {
+			if i == len(events) {
+				return nil, io.EOF
+			}
+			i++
+			return events[i-1], nil
+		}
+	}
+	end := model.MySQLOffset{File: "bin.000002", Pos: 300}
+
+	tests := []struct {
+		at   int64
+		want model.MySQLOffset
+	}{
+		{50, model.MySQLOffset{File: "bin.000001", Pos: 126}},
+		{100, model.MySQLOffset{File: "bin.000001", Pos: 126}},
+		{101, model.MySQLOffset{File: "bin.000002", Pos: 126}},
+		{200, model.MySQLOffset{File: "bin.000002", Pos: 126}},
+		{201, end},
+	}
+	for _, tt := range tests {
+		got, err := scanFor(source(), "bin.000001", time.Unix(tt.at, 0), end)
+		if err != nil {
+			t.Fatalf("%d: %v", tt.at, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%d: expected %v, got %v", tt.at, tt.want, got)
+		}
+	}
+
+	if _, err := scanFor(source(), "bin.000001", time.Unix(300, 0), model.MySQLOffset{File: "bin.000003", Pos: 4}); err == nil {
+		t.Fatal("expected the stream error when end is never reached")
+	}
+}
+
+func TestNewBinlogInspector_Start(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offset.binlog")
+	if err := util.SaveJSON(path, model.MySQLOffset{File: "bin.000001", Pos: 4}); err != nil {
+		t.Fatal(err)
+	}
+	start := model.MySQLOffset{File: "bin.000009", Pos: 120}
+
+	b, err := NewBinlogInspector(nil, model.MySQL, "shop", "u:p@tcp(db:3306)/shop", path, 1, nil, Options{Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.resumePoint(); got != start {
+		t.Fatalf("expected to start from %v, got %v", start, got)
+	}
+}
@@ -0,0 +1,165 @@
+package inspector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// checkPurged verifies the server still has the binlogs needed to resume from
+// off and applies the configured policy when it does not. It returns the
+// offset to start from.
+func (b *BinlogInspector) checkPurged(ctx context.Context, out chan<- model.Event, off model.MySQLOffset) (model.MySQLOffset, error) {
+	if b.db == nil || (off.File == "" && off.GTIDSet == "") {
+		return off, nil
+	}
+
+	logs, err := binaryLogs(ctx, b.db)
+	if err != nil {
+		log.Printf("[binlog] cannot verify binlog availability: %v", err)
+		return off, nil
+	}
+	if len(logs) == 0 {
+		return off, fatal(fmt.Errorf("binary logging is disabled on the server"))
+	}
+
+	// MariaDB has no gtid_purged; its offsets keep the file alongside the
+	// GTID position, so availability is checked by file.
+	var purged bool
+	if off.GTIDSet != "" && b.dbType == model.MySQL {
+		purged, err = b.gtidPurged(ctx, off.GTIDSet)
+		if err != nil {
+			log.Printf("[binlog] cannot verify gtid availability: %v", err)
+			return off, nil
+		}
+	} else {
+		purged = !contains(logs, off.File)
+	}
+
+	if !purged {
+		return off, nil
+	}
+
+	earliest := logs[0]
+	switch b.opts.OnPurged {
+	case config.OnPurgedEarliest:
+		next := model.MySQLOffset{File: earliest, Pos: 4}
+		msg := fmt.Sprintf("offset %s was purged from the server, resuming from %s; changes in between are lost", off, next)
+		log.Printf("[binlog] WARN %s", msg)
+
+		out <- model.NewWarningEvent(model.SourceType(b.dbType), next, time.Now(), model.WarningBinlogPurged, msg)
+		b.committed = next
+		return next, nil
+
+	case config.OnPurgedSnapshot:
+		log.Printf("[binlog] WARN offset %s was purged from the server, taking a fresh snapshot", off)
+		return b.snapshot(ctx, out)
+
+	default:
+		return off, fatal(fmt.Errorf("offset %s was purged from the server (earliest available binlog is %s); set database.on_purged to earliest or snapshot to recover", off, earliest))
+	}
+}
+
+// gtidPurged reports whether the server purged transactions not in executed.
+func (b *BinlogInspector) gtidPurged(ctx context.Context, executed string) (bool, error) {
+	var raw string
+	if err := b.db.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_purged").Scan(&raw); err != nil {
+		return false, err
+	}
+
+	serverPurged, err := mysql.ParseMysqlGTIDSet(raw)
+	if err != nil {
+		return false, err
+	}
+	ours, err := mysql.ParseMysqlGTIDSet(executed)
+	if err != nil {
+		return false, err
+	}
+	return !ours.Contain(serverPurged), nil
+}
+
+// binaryLogs returns the binlog files the server still has, oldest first.
+func binaryLogs(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		vals, err := scanStrings(rows)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, vals[0])
+	}
+	return names, rows.Err()
+}
+
+// currentPosition returns the server's current binlog write position.
+func currentPosition(ctx context.Context, db *sql.DB) (mysql.Position, error) {
+	rows, err := db.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	if err != nil {
+		// renamed from SHOW MASTER STATUS in MySQL 8.4
+		rows, err = db.QueryContext(ctx, "SHOW MASTER STATUS")
+		if err != nil {
+			return mysql.Position{}, err
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return mysql.Position{}, fmt.Errorf("binary logging is disabled on the server")
+	}
+	vals, err := scanStrings(rows)
+	if err != nil {
+		return mysql.Position{}, err
+	}
+
+	pos, err := strconv.ParseUint(vals[1], 10, 32)
+	if err != nil {
+		return mysql.Position{}, fmt.Errorf("invalid binlog position %q: %w", vals[1], err)
+	}
+	return mysql.Position{Name: vals[0], Pos: uint32(pos)}, nil
+}
+
+// scanStrings scans a row whose column count varies between server versions.
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]sql.NullString, len(cols))
+	dest := make([]any, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		out[i] = v.String
+	}
+	return out, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package inspector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// fakeResult is the canned answer to one query.
+type fakeResult struct {
+	cols  []string
+	types []string
+	rows  [][]driver.Value
+}
+
+// fakeDB is a database/sql driver answering queries from a fixed table.
+type fakeDB map[string]fakeResult
+
+func (f fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf("not supported") }
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.ResultNoRows, nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	res, ok := c.db[query]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return &fakeRows{res: res}, nil
+}
+
+type fakeRows struct {
+	res fakeResult
+	i   int
+}
+
+func (r *fakeRows) Columns() []string { return r.res.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.res.rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.rows[r.i])
+	r.i++
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.res.types) {
+		return r.res.types[i]
+	}
+	return ""
+}
+
+func (r *fakeRows) ColumnTypeNullable(int) (bool, bool) { return true, true }
+
+var purgedLogs = fakeDB{
+	"SHOW BINARY LOGS": {
+		cols: []string{"Log_name", "File_size", "Encrypted"},
+		rows: [][]driver.Value{
+			{[]byte("binlog.000007"), []byte("1024"), []byte("No")},
+			{[]byte("binlog.000008"), []byte("2048"), []byte("No")},
+		},
+	},
+	"SHOW BINARY LOG STATUS": {
+		cols: []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"},
+		rows: [][]driver.Value{{[]byte("binlog.000008"), []byte("880"), []byte(""), []byte(""), []byte("")}},
+	},
+	"SELECT @@GLOBAL.gtid_purged": {
+		cols: []string{"@@GLOBAL.gtid_purged"},
+		rows: [][]driver.Value{{[]byte("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-100")}},
+	},
+	"SELECT COLUMN_NAME, COLUMN_TYPE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?": {
+		cols: []string{"COLUMN_NAME", "COLUMN_TYPE"},
+		rows: [][]driver.Value{
+			{[]byte("id"), []byte("bigint unsigned")},
+			{[]byte("name"), []byte("varchar(64)")},
+		},
+	},
+	"SELECT * FROM `shop`.`users`": {
+		cols:  []string{"id", "name"},
+		types: []string{"UNSIGNED BIGINT", "VARCHAR"},
+		rows: [][]driver.Value{
+			{[]byte("1"), []byte("alice")},
+			{[]byte("2"), nil},
+		},
+	},
+}
+
+func newPurgedInspector(policy string) *BinlogInspector {
+	return &BinlogInspector{
+		db:     sql.OpenDB(purgedLogs),
+		dbType: model.MySQL,
+		opts:   Options{OnPurged: policy},
+		tableMeta: map[string]*tableMeta{
+			"shop.users": NewTableMeta("id"),
+		},
+	}
+}
+
+func TestCheckPurged_Available(t *testing.T) {
+	b := newPurgedInspector("")
+	off := model.MySQLOffset{File: "binlog.000008", Pos: 300}
+
+	got, err := b.checkPurged(context.Background(), make(chan model.Event), off)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != off {
+		t.Fatalf("expected %v, got %v", off, got)
+	}
+}
+
+func TestCheckPurged_Fail(t *testing.T) {
+	b := newPurgedInspector(config.OnPurgedFail)
+
+	_, err := b.checkPurged(context.Background(), make(chan model.Event), model.MySQLOffset{File: "binlog.000003", Pos: 300})
+	if err == nil || !IsFatal(err) {
+		t.Fatalf("expected fatal error, got %v", err)
+	}
+}
+
+func TestCheckPurged_Earliest(t *testing.T) {
+	b := newPurgedInspector(config.OnPurgedEarliest)
+	out := make(chan model.Event, 1)
+
+	got, err := b.checkPurged(context.Background(), out, model.MySQLOffset{File: "binlog.000003", Pos: 300})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := model.MySQLOffset{File: "binlog.000007", Pos: 4}
+	if got != want || b.resumePoint() != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	w, ok := (<-out).(*model.WarningEvent)
+	if !ok || w.Code() != model.WarningBinlogPurged {
+		t.Fatalf("expected purged warning event")
+	}
+}
+
+func TestCheckPurged_GTID(t *testing.T) {
+	b := newPurgedInspector("")
+
+	off := model.MySQLOffset{File: "binlog.000003", Pos: 300, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-150"}
+	if _, err := b.checkPurged(context.Background(), nil, off); err != nil {
+		t.Fatalf("gtid set covering gtid_purged should be available: %v", err)
+	}
+
+	off.GTIDSet = "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-50"
+	if _, err := b.checkPurged(context.Background(), nil, off); !IsFatal(err) {
+		t.Fatalf("expected fatal error, got %v", err)
+	}
+}
+
+func TestCheckPurged_Snapshot(t *testing.T) {
+	b := newPurgedInspector(config.OnPurgedSnapshot)
+	out := make(chan model.Event, 4)
+
+	got, err := b.checkPurged(context.Background(), out, model.MySQLOffset{File: "binlog.000003", Pos: 300})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(out)
+
+	want := model.MySQLOffset{File: "binlog.000008", Pos: 880}
+	if got != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	var events []model.Event
+	for evt := range out {
+		events = append(events, evt)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected rows and commit, got %d events", len(events))
+	}
+
+	rows := events[0].(*model.BinlogRowEvent)
+	change := rows.Changes()[0]
+	if change.Op != model.OpRead || len(change.Rows) != 2 {
+		t.Fatalf("unexpected snapshot change: %+v", change)
+	}
+	if change.Rows[0].PK["id"] != int64(1) || change.Rows[0].After["name"] != "alice" {
+		t.Fatalf("unexpected first row: %+v", change.Rows[0])
+	}
+	if change.Rows[1].After["name"] != nil {
+		t.Fatalf("expected NULL name, got %v", change.Rows[1].After["name"])
+	}
+	if change.Columns[0].Type != "bigint" || change.Columns[1].Type != "varchar" {
+		t.Fatalf("unexpected column types: %+v", change.Columns)
+	}
+
+	commit := events[1].(*model.TransactionBoundaryEvent)
+	if commit.Kind() != model.TxCommit || commit.TxID() != rows.TxID() || commit.Offset() != want {
+		t.Fatalf("unexpected commit: %+v", commit)
+	}
+}
@@ -0,0 +1,66 @@
+package inspector
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	sqldriver "github.com/go-sql-driver/mysql"
+)
+
+// fatalError marks errors that reconnecting cannot fix.
+type fatalError struct {
+	err error
+}
+
+func (e fatalError) Error() string { return e.err.Error() }
+func (e fatalError) Unwrap() error { return e.err }
+
+func fatal(err error) error {
+	return fatalError{err: err}
+}
+
+// IsFatal reports whether err needs operator action: bad credentials, missing
+// replication privileges, or a start position the server can no longer serve
+// (e.g. purged binlogs). Retrying such errors cannot succeed.
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var fe fatalError
+	if errors.As(err, &fe) {
+		return true
+	}
+
+	var me *mysql.MyError
+	if errors.As(err, &me) {
+		switch me.Code {
+		case mysql.ER_ACCESS_DENIED_ERROR,
+			mysql.ER_DBACCESS_DENIED_ERROR,
+			mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR,
+			mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG,
+			mysql.ER_MASTER_HAS_PURGED_REQUIRED_GTIDS,
+			mysql.ER_NO_BINARY_LOGGING:
+			return true
+		}
+	}
+
+	// the same denials as reported by database/sql connections
+	var de *sqldriver.MySQLError
+	if errors.As(err, &de) {
+		switch de.Number {
+		case mysql.ER_ACCESS_DENIED_ERROR, mysql.ER_DBACCESS_DENIED_ERROR, mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR:
+			return true
+		}
+	}
+	return false
+}
+
+func nextBackoff(cur, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		return max
+	}
+	return next
+}
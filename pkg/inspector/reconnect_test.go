@@ -0,0 +1,126 @@
+package inspector
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/util"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	sqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestIsFatal(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"io", errors.New("connection reset by peer"), false},
+		{"sync closed", replication.ErrSyncClosed, false},
+		{"purged", fmt.Errorf("sync: %w", &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG}), true},
+		{"auth", &mysql.MyError{Code: mysql.ER_ACCESS_DENIED_ERROR}, true},
+		{"privilege", fmt.Errorf("start: %w", &mysql.MyError{Code: mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR}), true},
+		{"other mysql", &mysql.MyError{Code: mysql.ER_LOCK_WAIT_TIMEOUT}, false},
+		{"marked", fatal(errors.New("bad offset")), true},
+		{"ping auth", fmt.Errorf("db connection failed: %w", &sqldriver.MySQLError{Number: mysql.ER_ACCESS_DENIED_ERROR}), true},
+		{"ping other", &sqldriver.MySQLError{Number: mysql.ER_LOCK_WAIT_TIMEOUT}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsFatal(tt.err); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	d := 500 * time.Millisecond
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		d = nextBackoff(d, 4*time.Second)
+		got = append(got, d)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("step %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestResumePoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offset.json")
+	if err := util.SaveJSON(path, model.MySQLOffset{File: "binlog.000003", Pos: 120}); err != nil {
+		t.Fatalf("save offset: %v", err)
+	}
+
+	b := &BinlogInspector{offsetPath: path}
+	if off := b.resumePoint(); off.File != "binlog.000003" || off.Pos != 120 {
+		t.Fatalf("expected persisted offset, got %v", off)
+	}
+
+	b.currentFile = "binlog.000004"
+	b.commitOffset(4096)
+	if off := b.resumePoint(); off.File != "binlog.000004" || off.Pos != 4096 {
+		t.Fatalf("expected last committed offset, got %v", off)
+	}
+}
+
+func TestCommitOffset_TracksGTID(t *testing.T) {
+	gset, err := mysql.ParseMysqlGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5")
+	if err != nil {
+		t.Fatalf("parse gtid set: %v", err)
+	}
+
+	b := &BinlogInspector{currentFile: "binlog.000001", gtidSet: gset}
+
+	b.txMeta.GTID = "3e11fa47-71ca-11e1-9e33-c80aa9429562:6"
+	off := b.commitOffset(500)
+	if off.GTIDSet != "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-6" {
+		t.Fatalf("unexpected gtid set: %q", off.GTIDSet)
+	}
+
+	b.gtidSet = nil
+	if off := b.commitOffset(600); off.GTIDSet != "" {
+		t.Fatalf("expected no gtid set when untracked, got %q", off.GTIDSet)
+	}
+}
+
+func TestAbortTx(t *testing.T) {
+	out := make(chan model.Event, 1)
+	b := &BinlogInspector{
+		dbType:      model.MySQL,
+		currentTxID: "tx:200",
+		inTx:        true,
+		txMeta:      model.TxMeta{GTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:7"},
+		committed:   model.MySQLOffset{File: "binlog.000001", Pos: 150},
+	}
+
+	b.abortTx(out)
+	close(out)
+
+	evt, ok := <-out
+	if !ok {
+		t.Fatalf("expected abort event")
+	}
+	boundary, ok := evt.(*model.TransactionBoundaryEvent)
+	if !ok || boundary.Kind() != model.TxAbort || boundary.TxID() != "tx:200" {
+		t.Fatalf("unexpected event: %#v", evt)
+	}
+	if b.currentTxID != "" || b.txMeta.GTID != "" {
+		t.Fatalf("transaction state not reset")
+	}
+
+	out = make(chan model.Event, 1)
+	b.abortTx(out)
+	if len(out) != 0 {
+		t.Fatalf("expected no event without open transaction")
+	}
+}
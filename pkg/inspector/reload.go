@@ -0,0 +1,109 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// Update changes what a running inspector captures.
+type Update struct {
+	Tables []config.Table
+	// Snapshot names tables, among those added, whose current rows are
+	// published before their changes.
+	Snapshot   []string
+	Statements config.Statements
+}
+
+// Reload queues u for the stream, which applies it at the next transaction
+// boundary so no transaction is captured under two table lists.
+func (b *BinlogInspector) Reload(u Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pending != nil {
+		// tables still waiting for their snapshot keep it
+		u.Snapshot = append(u.Snapshot, b.pending.Snapshot...)
+	}
+	b.pending = &u
+	if b.wake != nil {
+		b.wake()
+	}
+}
+
+// applyPending applies a queued Update, snapshotting the tables it asks for
+// at the current resume point. A failed snapshot is logged and the tables
+// are captured from here on; only cancellation is returned.
+func (b *BinlogInspector) applyPending(ctx context.Context, out chan<- model.Event) error {
+	b.mu.Lock()
+	u := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	if u == nil {
+		return nil
+	}
+
+	tables := make(map[string]*tableMeta, len(u.Tables))
+	var added []string
+	isAdded := map[string]bool{}
+	for _, t := range u.Tables {
+		key := fmt.Sprintf("%s.%s", b.schema, t.Name)
+		meta, ok := b.tableMeta[key]
+		if !ok {
+			meta = &tableMeta{pkIndex: -1}
+			added = append(added, key)
+			isAdded[key] = true
+		}
+		tables[key] = meta
+		if meta.pkName != t.PK {
+			meta.pkName = t.PK
+			if len(meta.columns) > 0 {
+				b.updatePKIndex(key)
+			}
+		}
+	}
+	for key := range b.tableMeta {
+		if tables[key] == nil {
+			log.Printf("[binlog] stop capturing %s", key)
+			if b.removed == nil {
+				b.removed = map[string]bool{}
+			}
+			b.removed[key] = true
+		}
+	}
+	for key := range tables {
+		delete(b.removed, key)
+	}
+	b.tableMeta = tables
+	b.opts.Statements = u.Statements
+
+	var snapshot []string
+	for _, name := range u.Snapshot {
+		key := fmt.Sprintf("%s.%s", b.schema, name)
+		if isAdded[key] {
+			snapshot = append(snapshot, key)
+		}
+	}
+	if len(added) > 0 {
+		log.Printf("[binlog] start capturing %s", strings.Join(added, ", "))
+	}
+	if len(snapshot) == 0 {
+		return nil
+	}
+
+	// Rows changed between the resume point and the snapshot are streamed
+	// again afterwards, so consumers converge on the current state.
+	offset := b.resumePoint()
+	txID := "snapshot:" + offset.String() + ":" + strings.Join(snapshot, ",")
+	if err := b.snapshotTables(ctx, out, snapshot, txID, offset); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		log.Printf("[binlog] %v; capturing only new changes of %s", err, strings.Join(snapshot, ", "))
+	}
+	return nil
+}
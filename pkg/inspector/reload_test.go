@@ -0,0 +1,79 @@
+package inspector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestReload_Tables(t *testing.T) {
+	b := &BinlogInspector{
+		schema: "shop",
+		inTx:   true,
+		tableMeta: map[string]*tableMeta{
+			"shop.users":  {pkName: "id", pkIndex: 0, columns: []string{"id", "email"}},
+			"shop.orders": {pkName: "id", pkIndex: 0, columns: []string{"id"}},
+		},
+	}
+	insert := func(table string) int {
+		out := make(chan model.Event, 1)
+		b.emitRowEvents(out, &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2}, &replication.RowsEvent{
+			Table: &replication.TableMapEvent{Schema: []byte("shop"), Table: []byte(table)},
+			Rows:  [][]any{{1, "a"}},
+		})
+		return len(out)
+	}
+
+	b.Reload(Update{
+		Tables:     []config.Table{{Name: "users", PK: "email"}, {Name: "invoices", PK: "id"}},
+		Statements: config.Statements{Capture: true},
+	})
+	if insert("orders") != 1 {
+		t.Fatal("reload applied before the stream reached a boundary")
+	}
+
+	if err := b.applyPending(context.Background(), nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if insert("orders") != 0 {
+		t.Fatal("removed table still captured")
+	}
+	if insert("customers") != 1 {
+		t.Fatal("never listed table no longer published")
+	}
+	if m := b.tableMeta["shop.users"]; m.pkName != "email" || m.pkIndex != 1 {
+		t.Fatalf("pk change not applied: %+v", m)
+	}
+	if m := b.tableMeta["shop.invoices"]; m == nil || m.pkName != "id" {
+		t.Fatal("added table not captured")
+	}
+	if !b.opts.Statements.Capture {
+		t.Fatal("statements setting not applied")
+	}
+	if b.pending != nil {
+		t.Fatal("reload applied twice")
+	}
+
+	b.Reload(Update{Tables: []config.Table{{Name: "orders", PK: "id"}}})
+	if err := b.applyPending(context.Background(), nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if insert("orders") != 1 {
+		t.Fatal("table added back not captured")
+	}
+}
+
+func TestReload_WakesIdleStream(t *testing.T) {
+	b := &BinlogInspector{}
+	waitCtx, _ := b.armWake(context.Background())
+
+	b.Reload(Update{})
+	select {
+	case <-waitCtx.Done():
+	default:
+		t.Fatal("reload did not wake the stream")
+	}
+}
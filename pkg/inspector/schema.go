@@ -0,0 +1,297 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// TableSchema describes a table for decoding binlogs whose table maps carry
+// no column names (binlog_row_metadata=MINIMAL) without asking the server.
+type TableSchema struct {
+	Columns []string `json:"columns"`
+	Engine  string   `json:"engine,omitempty"`
+}
+
+// DumpSchema reads the tables of schema from information_schema, keyed by
+// "schema.table", as a snapshot for LoadSchema.
+func DumpSchema(ctx context.Context, db *sql.DB, schema string, tables []string) (map[string]TableSchema, error) {
+	out := map[string]TableSchema{}
+	for _, table := range tables {
+		var engine sql.NullString
+		err := db.QueryRowContext(ctx, `
+			SELECT ENGINE
+			FROM INFORMATION_SCHEMA.TABLES
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		`, schema, table).Scan(&engine)
+		if err != nil {
+			return nil, fmt.Errorf("table %s.%s: %w", schema, table, err)
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT COLUMN_NAME
+			FROM INFORMATION_SCHEMA.COLUMNS
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+			ORDER BY ORDINAL_POSITION
+		`, schema, table)
+		if err != nil {
+			return nil, fmt.Errorf("table %s.%s: %w", schema, table, err)
+		}
+		ts := TableSchema{Engine: engine.String}
+		for rows.Next() {
+			var col string
+			if err := rows.Scan(&col); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ts.Columns = append(ts.Columns, col)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		out[schema+"."+table] = ts
+	}
+	return out, nil
+}
+
+// LoadSchema reads table schemas from a snapshot written by DumpSchema or
+// from a file of CREATE TABLE statements such as mysqldump --no-data writes.
+// Unqualified DDL table names belong to defaultSchema until a USE statement.
+func LoadSchema(path, defaultSchema string) (map[string]TableSchema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var out map[string]TableSchema
+		if err := json.Unmarshal(b, &out); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return out, nil
+	}
+
+	out, err := parseDDL(string(b), defaultSchema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+var (
+	createTable = regexp.MustCompile(`(?is)^CREATE\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?`)
+	useSchema   = regexp.MustCompile("(?is)^USE\\s+")
+	tableEngine = regexp.MustCompile(`(?i)\bENGINE\s*=?\s*(\w+)`)
+)
+
+// parseDDL collects the columns and engine of every CREATE TABLE statement
+// in ddl. Other statements are ignored.
+func parseDDL(ddl, defaultSchema string) (map[string]TableSchema, error) {
+	out := map[string]TableSchema{}
+	schema := defaultSchema
+
+	for _, stmt := range splitSQL(ddl, ';') {
+		stmt = strings.TrimSpace(stmt)
+
+		if loc := useSchema.FindStringIndex(stmt); loc != nil {
+			if name, _ := parseIdent(stmt[loc[1]:]); name != "" {
+				schema = name
+			}
+			continue
+		}
+		loc := createTable.FindStringIndex(stmt)
+		if loc == nil {
+			continue
+		}
+
+		rest := stmt[loc[1]:]
+		table, rest := parseIdent(rest)
+		tableSchema := schema
+		if strings.HasPrefix(rest, ".") {
+			tableSchema = table
+			table, rest = parseIdent(rest[1:])
+		}
+		rest = strings.TrimSpace(rest)
+		if table == "" || !strings.HasPrefix(rest, "(") {
+			// e.g. CREATE TABLE ... LIKE or AS SELECT
+			continue
+		}
+
+		end := closingParen(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated CREATE TABLE %s", table)
+		}
+		ts := TableSchema{}
+		for _, def := range splitSQL(rest[1:end], ',') {
+			if name, ok := columnName(strings.TrimSpace(def)); ok {
+				ts.Columns = append(ts.Columns, name)
+			}
+		}
+		if m := tableEngine.FindStringSubmatch(rest[end:]); m != nil {
+			ts.Engine = m[1]
+		}
+		out[tableSchema+"."+table] = ts
+	}
+	return out, nil
+}
+
+// columnName returns the column a CREATE TABLE definition declares; index
+// and constraint definitions declare none.
+func columnName(def string) (string, bool) {
+	if def == "" {
+		return "", false
+	}
+	if def[0] != '`' {
+		word := strings.ToUpper(strings.Fields(def)[0])
+		switch word {
+		case "PRIMARY", "KEY", "INDEX", "UNIQUE", "CONSTRAINT", "FOREIGN", "FULLTEXT", "SPATIAL", "CHECK", "PERIOD":
+			return "", false
+		}
+	}
+	name, _ := parseIdent(def)
+	return name, name != ""
+}
+
+// parseIdent reads a plain or backquoted identifier from the start of s.
+func parseIdent(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	if strings.HasPrefix(s, "`") {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '`' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '`' {
+				b.WriteByte('`')
+				i++
+				continue
+			}
+			return b.String(), s[i+1:]
+		}
+		return "", ""
+	}
+
+	i := 0
+	for i < len(s) && (s[i] == '_' || s[i] == '$' || s[i] >= '0' && s[i] <= '9' ||
+		s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= 0x80) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// closingParen returns the index of the parenthesis closing the one s starts
+// with, or -1.
+func closingParen(s string) int {
+	depth := 0
+	end := -1
+	scanSQL(s, func(i int, c byte, quoted bool) bool {
+		switch {
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				end = i
+				return false
+			}
+		}
+		return true
+	})
+	return end
+}
+
+// splitSQL splits s at sep outside quotes, comments and parentheses, and
+// drops the comments.
+func splitSQL(s string, sep byte) []string {
+	var parts []string
+	var cur strings.Builder
+	depth := 0
+	scanSQL(s, func(_ int, c byte, quoted bool) bool {
+		switch {
+		case quoted:
+		case c == sep && depth == 0:
+			parts = append(parts, cur.String())
+			cur.Reset()
+			return true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+		cur.WriteByte(c)
+		return true
+	})
+	if strings.TrimSpace(cur.String()) != "" {
+		parts = append(parts, cur.String())
+	}
+	return parts
+}
+
+// scanSQL calls fn with each byte of s, flagging those of quoted strings and
+// identifiers, until fn returns false. Comments are passed as a space.
+func scanSQL(s string, fn func(i int, c byte, quoted bool) bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`' && i+1 < len(s):
+				// the escaped byte cannot end the quote
+				if !fn(i, c, true) {
+					return
+				}
+				i++
+			case c == quote && i+1 < len(s) && s[i+1] == quote:
+				// a doubled quote is a literal one
+				if !fn(i, c, true) {
+					return
+				}
+				i++
+			case c == quote:
+				quote = 0
+			}
+			if !fn(i, s[i], true) {
+				return
+			}
+			continue
+		}
+
+		var skip int
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			if !fn(i, c, true) {
+				return
+			}
+			continue
+		case c == '#' || strings.HasPrefix(s[i:], "--") && (i+2 == len(s) || strings.IndexByte(" \t\r\n", s[i+2]) >= 0):
+			skip = strings.IndexByte(s[i:], '\n')
+		case strings.HasPrefix(s[i:], "/*"):
+			if skip = strings.Index(s[i+2:], "*/"); skip >= 0 {
+				skip += 4
+			}
+		default:
+			if !fn(i, c, false) {
+				return
+			}
+			continue
+		}
+
+		if skip < 0 {
+			return
+		}
+		if !fn(i, ' ', false) {
+			return
+		}
+		i += skip - 1
+	}
+}
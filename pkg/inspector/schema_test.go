@@ -0,0 +1,92 @@
+package inspector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+const dumpDDL = `
+-- MySQL dump 10.13
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+DROP TABLE IF EXISTS ` + "`users`" + `;
+CREATE TABLE ` + "`users`" + ` (
+  ` + "`id`" + ` bigint NOT NULL AUTO_INCREMENT,
+  ` + "`email`" + ` varchar(255) DEFAULT 'a;b,c' COMMENT 'it''s (unique)',
+  ` + "`amount`" + ` decimal(10,2) NOT NULL,
+  PRIMARY KEY (` + "`id`" + `),
+  UNIQUE KEY ` + "`email`" + ` (` + "`email`" + `),
+  CONSTRAINT ` + "`fk`" + ` FOREIGN KEY (` + "`id`" + `) REFERENCES ` + "`accounts`" + ` (` + "`id`" + `)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+USE billing;
+# a comment
+create table if not exists invoices (
+  id int primary key, -- inline comment
+  total int
+) engine MyISAM;
+CREATE TABLE ` + "`shop`.`orders`" + ` (id int, user_id int, KEY idx (user_id));
+CREATE TABLE copy LIKE invoices;
+`
+
+func TestParseDDL(t *testing.T) {
+	got, err := parseDDL(dumpDDL, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]TableSchema{
+		"shop.users":       {Columns: []string{"id", "email", "amount"}, Engine: "InnoDB"},
+		"billing.invoices": {Columns: []string{"id", "total"}, Engine: "MyISAM"},
+		"shop.orders":      {Columns: []string{"id", "user_id"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLoadSchema_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	snapshot := `{"shop.users": {"columns": ["id", "email"], "engine": "InnoDB"}}`
+	if err := os.WriteFile(path, []byte(snapshot), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadSchema(path, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts := got["shop.users"]; !reflect.DeepEqual(ts.Columns, []string{"id", "email"}) || ts.Engine != "InnoDB" {
+		t.Fatalf("unexpected schema %+v", got)
+	}
+}
+
+func TestOnTableMap_SuppliedSchema(t *testing.T) {
+	b := &BinlogInspector{
+		tableMeta: map[string]*tableMeta{"shop.users": NewTableMeta("id")},
+		opts: Options{Schema: map[string]TableSchema{
+			"shop.users": {Columns: []string{"email", "id"}, Engine: "MyISAM"},
+		}},
+	}
+
+	// no column names in the table map, as with binlog_row_metadata=MINIMAL
+	b.onTableMap(&replication.TableMapEvent{
+		Schema:      []byte("shop"),
+		Table:       []byte("users"),
+		ColumnCount: 2,
+		ColumnType:  []byte{0x0f, 0x03},
+		ColumnMeta:  []uint16{0, 0},
+		NullBitmap:  []byte{0},
+	})
+
+	meta := b.tableMeta["shop.users"]
+	if !reflect.DeepEqual(meta.columns, []string{"email", "id"}) || meta.pkIndex != 1 {
+		t.Fatalf("expected columns from the supplied schema, got %v pk %d", meta.columns, meta.pkIndex)
+	}
+	if !isNonTransactional(meta.engine) {
+		t.Fatalf("expected the supplied engine, got %q", meta.engine)
+	}
+}
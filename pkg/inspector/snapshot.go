@@ -0,0 +1,282 @@
+package inspector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/shopspring/decimal"
+)
+
+const snapshotBatchRows = 1000
+
+// snapshot emits every row of the captured tables as reads in a single
+// transaction and returns the binlog position to stream from afterwards.
+//
+// The position is read before the snapshot transaction starts, so changes
+// committed in between are delivered twice rather than lost.
+func (b *BinlogInspector) snapshot(ctx context.Context, out chan<- model.Event) (model.MySQLOffset, error) {
+	pos, err := currentPosition(ctx, b.db)
+	if err != nil {
+		return model.MySQLOffset{}, fmt.Errorf("read binlog position: %w", err)
+	}
+	offset := model.MySQLOffset{File: pos.Name, Pos: pos.Pos}
+
+	keys := make([]string, 0, len(b.tableMeta))
+	for key := range b.tableMeta {
+		keys = append(keys, key)
+	}
+	if err := b.snapshotTables(ctx, out, keys, "snapshot:"+offset.String(), offset); err != nil {
+		return model.MySQLOffset{}, err
+	}
+	b.committed = offset
+
+	return offset, nil
+}
+
+// snapshotTables emits every row of the tables at keys as reads in one
+// transaction txID carrying offset.
+func (b *BinlogInspector) snapshotTables(ctx context.Context, out chan<- model.Event, keys []string, txID string, offset model.MySQLOffset) error {
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"); err != nil {
+		return fmt.Errorf("start snapshot: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	keys = slices.Sorted(slices.Values(keys))
+	b.currentTxID = txID
+	now := time.Now()
+
+	for _, key := range keys {
+		n, err := b.snapshotTable(ctx, conn, out, key, offset, now)
+		if err != nil {
+			// drop the rows emitted so far
+			out <- model.NewTransactionBoundaryEvent(model.SourceType(b.dbType), offset, now, b.currentTxID, model.TxAbort)
+			b.currentTxID = ""
+			return fmt.Errorf("snapshot %s: %w", key, err)
+		}
+		log.Printf("[binlog] snapshot %s: %d rows", key, n)
+	}
+
+	out <- model.NewTransactionEndEvent(model.SourceType(b.dbType), offset, now, b.currentTxID, model.TxCommit, model.TxMeta{CommitTime: now})
+	b.currentTxID = ""
+	return nil
+}
+
+func (b *BinlogInspector) snapshotTable(ctx context.Context, conn *sql.Conn, out chan<- model.Event, key string, offset model.MySQLOffset, now time.Time) (int, error) {
+	schema, table := splitKey(key)
+	meta := b.tableMeta[key]
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT * FROM `%s`.`%s`", schema, table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	defs, err := columnDefinitions(ctx, conn, schema, table)
+	if err != nil {
+		return 0, err
+	}
+
+	meta.columns = make([]string, len(types))
+	meta.columnTypes = make([]string, len(types))
+	meta.sqlTypes = make([]string, len(types))
+	meta.nullable = make([]bool, len(types))
+	members := make([][]string, len(types))
+	for i, ct := range types {
+		meta.columns[i] = ct.Name()
+		meta.columnTypes[i] = sqlTypeName(ct.DatabaseTypeName())
+		meta.sqlTypes[i] = defs[ct.Name()]
+		meta.nullable[i], _ = ct.Nullable()
+		members[i] = enumMembers(defs[ct.Name()])
+	}
+	b.updatePKIndex(key)
+	columns := meta.describeColumns()
+
+	emit := func(batch []model.RowData) {
+		out <- model.NewBinlogRowEvent(model.SourceType(b.dbType), offset, now, b.currentTxID, []model.RowChange{{
+			Schema:  schema,
+			Table:   table,
+			Op:      model.OpRead,
+			Columns: columns,
+			Rows:    batch,
+		}})
+	}
+
+	var batch []model.RowData
+	total := 0
+	for rows.Next() {
+		vals := make([]any, len(types))
+		dest := make([]any, len(types))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return total, err
+		}
+		for i, v := range vals {
+			vals[i] = snapshotValue(meta.columnTypes[i], members[i], v)
+		}
+
+		batch = append(batch, model.RowData{
+			PK:    extractPK(meta, vals),
+			After: rowToMap(meta.columns, vals),
+		})
+		total++
+
+		if len(batch) == snapshotBatchRows {
+			emit(batch)
+			batch = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return total, err
+	}
+	if len(batch) > 0 {
+		emit(batch)
+	}
+	return total, nil
+}
+
+// sqlTypeName maps a driver type name to the names used for binlog columns
+// (see typeName), so snapshot and streamed rows share one schema.
+func sqlTypeName(dbType string) string {
+	t := strings.ToLower(strings.TrimPrefix(dbType, "UNSIGNED "))
+	switch t {
+	case "tinytext", "text", "mediumtext", "longtext", "tinyblob", "mediumblob", "longblob":
+		return "blob"
+	case "binary":
+		return "char"
+	case "varbinary":
+		return "varchar"
+	case "":
+		return "unknown"
+	default:
+		return t
+	}
+}
+
+// snapshotValue converts a text-protocol value to the Go type the binlog
+// decoder produces for the column, so snapshot and streamed rows encode
+// alike. members lists the values of an enum or set column.
+func snapshotValue(typ string, members []string, v any) any {
+	if t, ok := v.(time.Time); ok && typ == "date" {
+		if t.IsZero() {
+			return "0000-00-00"
+		}
+		return t.Format(time.DateOnly)
+	}
+
+	raw, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+
+	s := string(raw)
+	switch typ {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "year":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return n
+		}
+	case "float", "double":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "decimal":
+		if d, err := decimal.NewFromString(s); err == nil {
+			return d
+		}
+	case "bit":
+		var n int64
+		for _, c := range raw {
+			n = n<<8 | int64(c)
+		}
+		return n
+	case "enum":
+		// the binlog logs the 1-based index, 0 for the empty error value
+		return int64(slices.Index(members, s) + 1)
+	case "set":
+		var n int64
+		for _, m := range strings.Split(s, ",") {
+			if i := slices.Index(members, m); i >= 0 {
+				n |= 1 << i
+			}
+		}
+		return n
+	case "blob", "geometry", "json":
+		return raw
+	}
+	return s
+}
+
+// columnDefinitions returns the full type of each column of table, such as
+// varchar(255) or enum('a','b').
+func columnDefinitions(ctx context.Context, conn *sql.Conn, schema, table string) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT COLUMN_NAME, COLUMN_TYPE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("table %s.%s: %w", schema, table, err)
+	}
+	defer rows.Close()
+
+	defs := map[string]string{}
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		defs[name] = typ
+	}
+	return defs, rows.Err()
+}
+
+// enumMembers parses the values of an enum('a','b') or set(...) column type,
+// returning nil for other types.
+func enumMembers(typ string) []string {
+	lower := strings.ToLower(typ)
+	if !strings.HasPrefix(lower, "enum(") && !strings.HasPrefix(lower, "set(") {
+		return nil
+	}
+
+	var members []string
+	var cur strings.Builder
+	quoted := false
+	body := typ[strings.IndexByte(typ, '(')+1:]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(body) && body[i+1] == '\'':
+			cur.WriteByte('\'')
+			i++
+		case c == '\'':
+			quoted = !quoted
+			if !quoted {
+				members = append(members, cur.String())
+				cur.Reset()
+			}
+		case quoted:
+			cur.WriteByte(c)
+		}
+	}
+	return members
+}
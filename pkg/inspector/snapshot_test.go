@@ -0,0 +1,116 @@
+package inspector
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/format"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/shopspring/decimal"
+)
+
+func TestEnumMembers(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want []string
+	}{
+		{"enum('a','b')", []string{"a", "b"}},
+		{"set('x','it''s','a,b')", []string{"x", "it's", "a,b"}},
+		{"varchar(255)", nil},
+	}
+	for _, tt := range tests {
+		if got := enumMembers(tt.typ); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %q, got %q", tt.typ, tt.want, got)
+		}
+	}
+}
+
+// TestSnapshotValue_MatchesStream encodes a snapshot row and the same row
+// streamed from the binlog, which must come out identical.
+func TestSnapshotValue_MatchesStream(t *testing.T) {
+	names := []string{"id", "status", "tags", "amount", "doc", "born"}
+
+	// as the binlog decoder delivers the row
+	streamed := &BinlogInspector{
+		currentFile: "binlog.000001",
+		inTx:        true,
+		tableMeta:   map[string]*tableMeta{"shop.users": NewTableMeta("id")},
+	}
+	tableMap := &replication.TableMapEvent{
+		Schema:      []byte("shop"),
+		Table:       []byte("users"),
+		ColumnCount: 6,
+		ColumnName:  [][]byte{[]byte("id"), []byte("status"), []byte("tags"), []byte("amount"), []byte("doc"), []byte("born")},
+		ColumnType: []byte{
+			mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_STRING, mysql.MYSQL_TYPE_STRING,
+			mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_JSON, mysql.MYSQL_TYPE_DATE,
+		},
+		ColumnMeta: []uint16{0, uint16(mysql.MYSQL_TYPE_ENUM)<<8 | 1, uint16(mysql.MYSQL_TYPE_SET)<<8 | 1, 10<<8 | 2, 4, 0},
+		NullBitmap: []byte{0},
+	}
+	streamed.onTableMap(tableMap)
+	out := make(chan model.Event, 1)
+	streamed.emitRowEvents(out, &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 100}, &replication.RowsEvent{
+		Table: tableMap,
+		Rows:  [][]interface{}{{int64(1), int64(2), int64(5), decimal.RequireFromString("1.50"), []byte(`{"a":1}`), "2020-01-02"}},
+	})
+	close(out)
+	stream := (<-out).(*model.BinlogRowEvent).Changes()[0]
+
+	// as the snapshot query returns it over the text protocol
+	driverTypes := []string{"BIGINT", "ENUM", "SET", "DECIMAL", "JSON", "DATE"}
+	raw := []any{[]byte("1"), []byte("b"), []byte("x,z"), []byte("1.50"), []byte(`{"a":1}`), time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	members := [][]string{nil, enumMembers("enum('a','b')"), enumMembers("set('x','y','z')"), nil, nil, nil}
+	meta := &tableMeta{pkName: "id", columns: names, nullable: make([]bool, len(names))}
+	vals := make([]any, len(raw))
+	for i, v := range raw {
+		meta.columnTypes = append(meta.columnTypes, sqlTypeName(driverTypes[i]))
+		vals[i] = snapshotValue(meta.columnTypes[i], members[i], v)
+	}
+	meta.pkIndex = 0
+	snapshot := model.RowChange{
+		Schema:  "shop",
+		Table:   "users",
+		Op:      model.OpRead,
+		Columns: meta.describeColumns(),
+		Rows:    []model.RowData{{PK: extractPK(meta, vals), After: rowToMap(meta.columns, vals)}},
+	}
+
+	for i, c := range snapshot.Columns {
+		if c.Type != stream.Columns[i].Type {
+			t.Fatalf("column %s: snapshot type %s, streamed %s", c.Name, c.Type, stream.Columns[i].Type)
+		}
+	}
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/compatibility") {
+			_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": true})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": 1})
+	}))
+	defer registry.Close()
+	enc := format.NewAvroEncoder("dbserver1", format.NewRegistry(registry.URL))
+
+	encode := func(change model.RowChange) []byte {
+		evt := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 100}, time.Unix(1700000000, 0), "tx-1", []model.RowChange{change}, model.TxMeta{})
+		msgs, err := enc.Encode(evt)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("encode: %d messages, %v", len(msgs), err)
+		}
+		return msgs[0].Value
+	}
+	// only the op tells them apart
+	stream.Op = model.OpRead
+	if s, b := encode(snapshot), encode(stream); !bytes.Equal(s, b) {
+		t.Fatalf("snapshot and streamed rows encode differently:\n%x\n%x", s, b)
+	}
+}
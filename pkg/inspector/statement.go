@@ -0,0 +1,111 @@
+package inspector
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// captureStatement returns the statement to attach to the following row
+// changes, or "" when statement capture is off.
+func (b *BinlogInspector) captureStatement(query []byte) string {
+	opts := b.opts.Statements
+	if !opts.Capture {
+		return ""
+	}
+
+	q := string(query)
+	if opts.Redact {
+		q = redactSQL(q)
+	}
+	return truncateStatement(q, opts.MaxLength)
+}
+
+// truncateStatement cuts q to at most max bytes on a rune boundary, marking
+// the cut with "..." when it fits. max <= 0 keeps q whole.
+func truncateStatement(q string, max int) string {
+	if max <= 0 || len(q) <= max {
+		return q
+	}
+	const marker = "..."
+	cut, suffix := max-len(marker), marker
+	if cut <= 0 {
+		cut, suffix = max, ""
+	}
+	for cut > 0 && !utf8.RuneStart(q[cut]) {
+		cut--
+	}
+	return q[:cut] + suffix
+}
+
+// redactSQL replaces string and numeric literals with ?, keeping identifiers,
+// keywords and comments, so statements can be logged without the values they
+// carry.
+func redactSQL(q string) string {
+	var out strings.Builder
+	out.Grow(len(q))
+
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == '\'' || c == '"':
+			i = skipQuoted(q, i)
+			out.WriteByte('?')
+
+		case c == '`':
+			end := strings.IndexByte(q[i+1:], '`')
+			if end < 0 {
+				out.WriteString(q[i:])
+				return out.String()
+			}
+			out.WriteString(q[i : i+end+2])
+			i += end + 2
+
+		case c == '/' && strings.HasPrefix(q[i:], "/*"):
+			end := strings.Index(q[i+2:], "*/")
+			if end < 0 {
+				out.WriteString(q[i:])
+				return out.String()
+			}
+			out.WriteString(q[i : i+end+4])
+			i += end + 4
+
+		case isDigit(c) && (i == 0 || !isIdentByte(q[i-1])):
+			for i < len(q) && (isIdentByte(q[i]) || q[i] == '.') {
+				i++
+			}
+			out.WriteByte('?')
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// skipQuoted returns the index after the string literal starting at q[i],
+// honouring backslash escapes and doubled quotes.
+func skipQuoted(q string, i int) int {
+	quote := q[i]
+	for i++; i < len(q); i++ {
+		switch q[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(q) && q[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(q)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
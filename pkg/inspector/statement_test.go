@@ -0,0 +1,100 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestRedactSQL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"INSERT INTO orders VALUES (1, 'alice', 2.50)", "INSERT INTO orders VALUES (?, ?, ?)"},
+		{`UPDATE t1 SET name = "o\"neil", note = 'it''s' WHERE id = 0x1F`, "UPDATE t1 SET name = ?, note = ? WHERE id = ?"},
+		{"DELETE FROM `table 2` WHERE col3 > -5 /* batch 7 */", "DELETE FROM `table 2` WHERE col3 > -? /* batch 7 */"},
+		{"SELECT 'unterminated", "SELECT ?"},
+	}
+
+	for _, tt := range tests {
+		if got := redactSQL(tt.in); got != tt.want {
+			t.Fatalf("redactSQL(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestTruncateStatement(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"INSERT INTO t VALUES (1)", 0, "INSERT INTO t VALUES (1)"},
+		{"INSERT INTO t VALUES (1)", 9, "INSERT..."},
+		{"SELECT 'héllo'", 13, "SELECT 'h..."},
+		{"INSERT INTO t VALUES (1)", 2, "IN"},
+	}
+
+	for _, tt := range tests {
+		got := truncateStatement(tt.in, tt.max)
+		if got != tt.want {
+			t.Fatalf("truncateStatement(%q, %d): expected %q, got %q", tt.in, tt.max, tt.want, got)
+		}
+		if tt.max > 0 && len(got) > tt.max {
+			t.Fatalf("truncateStatement(%q, %d): %d bytes over the limit", tt.in, tt.max, len(got)-tt.max)
+		}
+	}
+}
+
+func TestHandleEvent_RowsQuery(t *testing.T) {
+	rowsQuery := func(pos uint32, q string) *replication.BinlogEvent {
+		return recorded(pos, replication.ROWS_QUERY_EVENT, &replication.RowsQueryEvent{Query: []byte(q)})
+	}
+
+	tests := []struct {
+		name string
+		opts config.Statements
+		want string
+	}{
+		{name: "off", opts: config.Statements{}, want: ""},
+		{name: "capture", opts: config.Statements{Capture: true}, want: "INSERT INTO orders VALUES (1, 'alice')"},
+		{name: "redact and truncate", opts: config.Statements{Capture: true, Redact: true, MaxLength: 28}, want: "INSERT INTO orders VALUES..."},
+	}
+
+	for _, tt := range tests {
+		b := &BinlogInspector{
+			currentFile: "binlog.000001",
+			opts:        Options{Statements: tt.opts},
+			tableMeta: map[string]*tableMeta{
+				"shop.orders": {pkName: "id", columns: []string{"id"}},
+			},
+		}
+
+		out := make(chan model.Event, 8)
+		for _, ev := range []*replication.BinlogEvent{
+			query(150, "BEGIN"),
+			rowsQuery(175, "INSERT INTO orders VALUES (1, 'alice')"),
+			insert(200, "orders", 1),
+			xid(250),
+		} {
+			b.handleEvent(out, ev)
+		}
+		close(out)
+
+		found := false
+		for evt := range out {
+			if rows, ok := evt.(*model.BinlogRowEvent); ok {
+				found = true
+				if got := rows.Changes()[0].Statement; got != tt.want {
+					t.Fatalf("%s: expected statement %q, got %q", tt.name, tt.want, got)
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("%s: no row event emitted", tt.name)
+		}
+	}
+}
@@ -1,37 +1,70 @@
 package inspector
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
+	sqldriver "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/pkg/parser/charset"
 )
 
+// parseDSN reads the replication connection settings with the driver's own
+// parser, so they always match the SQL connection's.
 func (b *BinlogInspector) parseDSN() error {
-	parts := strings.Split(b.dsn, "@tcp(")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid dsn")
+	dc, err := sqldriver.ParseDSN(b.dsn)
+	if err != nil {
+		return fmt.Errorf("invalid dsn %s: %w", config.MaskDSN(b.dsn), err)
 	}
 
-	auth := strings.Split(parts[0], ":")
-	addr := strings.Split(strings.TrimSuffix(parts[1], ")"), ":")
+	b.user = dc.User
+	b.password = dc.Passwd
+	b.tls = dc.TLS
 
-	b.user = auth[0]
-	b.password = auth[1]
-	b.host = addr[0]
-	b.port = 3306
-
-	if len(addr) == 2 {
-		if _, err := fmt.Sscanf(addr[1], "%d", &b.port); err != nil {
-			return fmt.Errorf("invalid port in DSN: %w", err)
+	switch dc.Net {
+	case "unix":
+		// the syncer dials a port-less, path-like host as a unix socket
+		b.host, b.port = dc.Addr, 0
+	case "tcp", "tcp4", "tcp6":
+		host, port, err := net.SplitHostPort(dc.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid address in dsn: %w", err)
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid port in dsn: %w", err)
 		}
+		b.host, b.port = host, uint16(p)
+	default:
+		return fmt.Errorf("unsupported network %q in dsn", dc.Net)
 	}
-
 	return nil
 }
 
+// addr is where the replication connection goes, for logging.
+func (b *BinlogInspector) addr() string {
+	if b.port == 0 {
+		return b.host
+	}
+	return net.JoinHostPort(b.host, strconv.Itoa(int(b.port)))
+}
+
 func (b *BinlogInspector) fetchColumns(schema, table string) []string {
+	if ts, ok := b.opts.Schema[schema+"."+table]; ok {
+		return ts.Columns
+	}
+	if b.db == nil {
+		return nil
+	}
+
 	query := `
 		SELECT COLUMN_NAME
 		FROM INFORMATION_SCHEMA.COLUMNS
@@ -58,11 +91,76 @@ func (b *BinlogInspector) fetchColumns(schema, table string) []string {
 	return cols
 }
 
+func (b *BinlogInspector) fetchEngine(schema, table string) string {
+	if ts, ok := b.opts.Schema[schema+"."+table]; ok {
+		return ts.Engine
+	}
+	if b.db == nil {
+		return ""
+	}
+
+	var engine sql.NullString
+	err := b.db.QueryRow(`
+		SELECT ENGINE
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, schema, table).Scan(&engine)
+	if err != nil {
+		log.Printf("[binlog] failed to query engine for table %s.%s: %v", schema, table, err)
+		return ""
+	}
+	return engine.String
+}
+
+// isNonTransactional reports whether changes to tables of this engine persist
+// when the enclosing transaction rolls back.
+func isNonTransactional(engine string) bool {
+	switch strings.ToUpper(engine) {
+	case "MYISAM", "MRG_MYISAM", "MERGE", "MEMORY", "HEAP", "CSV", "ARCHIVE", "BLACKHOLE", "ARIA":
+		return true
+	default:
+		return false
+	}
+}
+
+// savepointName extracts the name from SAVEPOINT and ROLLBACK TO [SAVEPOINT]
+// statements.
+func savepointName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Trim(fields[len(fields)-1], "`;")
+}
+
 func isDML(e *replication.QueryEvent) bool {
 	q := string(e.Query)
 	return strings.HasPrefix(q, "INSERT") || strings.HasPrefix(q, "UPDATE") || strings.HasPrefix(q, "DELETE")
 }
 
+// truncateTarget returns the table a TRUNCATE [TABLE] statement empties,
+// qualified with schema when the statement does not name one.
+func truncateTarget(query, schema string) (string, bool) {
+	words := strings.Fields(query)
+	if len(words) < 2 || !strings.EqualFold(words[0], "TRUNCATE") {
+		return "", false
+	}
+
+	target := words[1]
+	if strings.EqualFold(target, "TABLE") {
+		if len(words) < 3 {
+			return "", false
+		}
+		target = words[2]
+	}
+
+	target = strings.ReplaceAll(strings.TrimRight(target, ";"), "`", "")
+	if !strings.Contains(target, ".") {
+		target = schema + "." + target
+	}
+	return target, true
+}
+
 func extractPK(meta *tableMeta, row []interface{}) map[string]any {
 	if meta.pkIndex >= 0 && meta.pkIndex < len(row) {
 		return map[string]any{meta.pkName: row[meta.pkIndex]}
@@ -75,6 +173,72 @@ func extractPK(meta *tableMeta, row []interface{}) map[string]any {
 	return map[string]any{}
 }
 
+// imagePK is extractPK for a row image that may omit columns; it returns nil
+// when the primary key column was not logged.
+func imagePK(meta *tableMeta, row []interface{}, skipped []int) map[string]any {
+	if slices.Contains(skipped, meta.pkIndex) {
+		return nil
+	}
+	return extractPK(meta, row)
+}
+
+// imageToMap is rowToMap for row images that may omit columns: skipped
+// columns are left out rather than reported as NULL, and partial JSON
+// updates are returned as diffs instead of values.
+func imageToMap(cols []string, row []interface{}, skipped []int) (map[string]any, map[string]model.JSONDiff) {
+	m := rowToMap(cols, row)
+
+	name := func(i int) string {
+		if len(cols) == 0 {
+			return fmt.Sprintf("col_%d", i)
+		}
+		if i < len(cols) {
+			return cols[i]
+		}
+		return ""
+	}
+
+	for _, i := range skipped {
+		delete(m, name(i))
+	}
+
+	var diffs map[string]model.JSONDiff
+	for i, v := range row {
+		d, ok := v.(*replication.JsonDiff)
+		if !ok || d == nil {
+			continue
+		}
+		if diffs == nil {
+			diffs = map[string]model.JSONDiff{}
+		}
+		diffs[name(i)] = model.JSONDiff{
+			Op:    strings.ToLower(d.Op.String()),
+			Path:  d.Path,
+			Value: d.Value,
+		}
+		delete(m, name(i))
+	}
+	return m, diffs
+}
+
+// rowsOp maps every rows event variant (v0, v1 and v2, MySQL partial JSON
+// updates and MariaDB compressed events) to its operation.
+func rowsOp(t replication.EventType) (model.OpType, bool) {
+	switch t {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2,
+		replication.MARIADB_WRITE_ROWS_COMPRESSED_EVENT_V1:
+		return model.OpInsert, true
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2,
+		replication.PARTIAL_UPDATE_ROWS_EVENT, replication.MARIADB_UPDATE_ROWS_COMPRESSED_EVENT_V1:
+		return model.OpUpdate, true
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2,
+		replication.MARIADB_DELETE_ROWS_COMPRESSED_EVENT_V1:
+		return model.OpDelete, true
+	default:
+		return "", false
+	}
+}
+
 func rowToMap(cols []string, row []interface{}) map[string]any {
 	m := make(map[string]any, len(row))
 
@@ -141,3 +305,202 @@ func (b *BinlogInspector) updatePKIndex(key string) {
 		meta.pkIndex = 0
 	}
 }
+
+func columnTypes(e *replication.TableMapEvent) ([]string, []bool) {
+	types := make([]string, len(e.ColumnType))
+	nullable := make([]bool, len(e.ColumnType))
+
+	for i, tp := range e.ColumnType {
+		if tp == mysql.MYSQL_TYPE_STRING && i < len(e.ColumnMeta) {
+			switch rt := byte(e.ColumnMeta[i] >> 8); rt {
+			case mysql.MYSQL_TYPE_ENUM, mysql.MYSQL_TYPE_SET:
+				tp = rt
+			}
+		}
+		types[i] = typeName(tp)
+		_, nullable[i] = e.Nullable(i)
+	}
+
+	return types, nullable
+}
+
+// intWidths are the default display widths of the integer types, signed and
+// unsigned, as SHOW CREATE TABLE prints them.
+var intWidths = map[string][2]int{
+	"tinyint":   {4, 3},
+	"smallint":  {6, 5},
+	"mediumint": {9, 8},
+	"int":       {11, 10},
+	"bigint":    {20, 20},
+}
+
+// sqlTypes returns the full column types the table map describes. Lengths
+// of character columns, signedness and enum and set values are only logged
+// with binlog_row_metadata=FULL and are left out otherwise.
+func sqlTypes(e *replication.TableMapEvent) []string {
+	types, _ := columnTypes(e)
+	unsigned := e.UnsignedMap()
+	collations := e.CollationMap()
+	enums := e.EnumStrValueMap()
+	sets := e.SetStrValueMap()
+
+	out := make([]string, len(types))
+	for i, typ := range types {
+		var meta uint16
+		if i < len(e.ColumnMeta) {
+			meta = e.ColumnMeta[i]
+		}
+		collation, hasCollation := collations[i]
+		binary := hasCollation && collation == binaryCollation
+
+		switch typ {
+		case "tinyint", "smallint", "mediumint", "int", "bigint":
+			if unsigned[i] {
+				typ = fmt.Sprintf("%s(%d)", typ, intWidths[typ][1])
+			} else {
+				typ = fmt.Sprintf("%s(%d)", typ, intWidths[typ][0])
+			}
+		case "decimal":
+			typ = fmt.Sprintf("decimal(%d,%d)", meta>>8, meta&0xff)
+		case "bit":
+			typ = fmt.Sprintf("bit(%d)", (meta>>8)*8+meta&0xff)
+		case "datetime", "timestamp", "time":
+			if e.ColumnType[i] != mysql.MYSQL_TYPE_DATETIME && e.ColumnType[i] != mysql.MYSQL_TYPE_TIMESTAMP &&
+				e.ColumnType[i] != mysql.MYSQL_TYPE_TIME && meta > 0 {
+				typ = fmt.Sprintf("%s(%d)", typ, meta)
+			}
+		case "varchar":
+			if binary {
+				typ = fmt.Sprintf("varbinary(%d)", meta)
+			} else if n := charLength(int(meta), collation, hasCollation); n >= 0 {
+				typ = fmt.Sprintf("varchar(%d)", n)
+			}
+		case "char":
+			// the length's high bits are folded into the real type byte
+			length := int(meta & 0xff)
+			if b0 := byte(meta >> 8); b0&0x30 != 0x30 {
+				length |= int((b0&0x30)^0x30) << 4
+			}
+			if binary {
+				typ = fmt.Sprintf("binary(%d)", length)
+			} else if n := charLength(length, collation, hasCollation); n >= 0 {
+				typ = fmt.Sprintf("char(%d)", n)
+			}
+		case "blob":
+			prefix := [...]string{"tiny", "", "medium", "long"}[min(max(int(meta), 1), 4)-1]
+			if hasCollation && !binary {
+				typ = prefix + "text"
+			} else {
+				typ = prefix + "blob"
+			}
+		case "enum":
+			if values, ok := enums[i]; ok {
+				typ = "enum(" + quoteValues(values) + ")"
+			}
+		case "set":
+			if values, ok := sets[i]; ok {
+				typ = "set(" + quoteValues(values) + ")"
+			}
+		}
+		if unsigned[i] {
+			typ += " unsigned"
+		}
+		out[i] = typ
+	}
+	return out
+}
+
+// binaryCollation is the collation id of the binary character set.
+const binaryCollation = 63
+
+// charLength converts a column's length in bytes to characters, or returns
+// -1 when the character set is unknown.
+func charLength(bytes int, collation uint64, ok bool) int {
+	if !ok {
+		return -1
+	}
+	co, err := charset.GetCollationByID(int(collation))
+	if err != nil {
+		return -1
+	}
+	cs, err := charset.GetCharsetInfo(co.CharsetName)
+	if err != nil || cs.Maxlen == 0 {
+		return -1
+	}
+	return bytes / cs.Maxlen
+}
+
+func quoteValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return strings.Join(quoted, ",")
+}
+
+func typeName(tp byte) string {
+	switch tp {
+	case mysql.MYSQL_TYPE_TINY:
+		return "tinyint"
+	case mysql.MYSQL_TYPE_SHORT:
+		return "smallint"
+	case mysql.MYSQL_TYPE_INT24:
+		return "mediumint"
+	case mysql.MYSQL_TYPE_LONG:
+		return "int"
+	case mysql.MYSQL_TYPE_LONGLONG:
+		return "bigint"
+	case mysql.MYSQL_TYPE_FLOAT:
+		return "float"
+	case mysql.MYSQL_TYPE_DOUBLE:
+		return "double"
+	case mysql.MYSQL_TYPE_DECIMAL, mysql.MYSQL_TYPE_NEWDECIMAL:
+		return "decimal"
+	case mysql.MYSQL_TYPE_BIT:
+		return "bit"
+	case mysql.MYSQL_TYPE_YEAR:
+		return "year"
+	case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE:
+		return "date"
+	case mysql.MYSQL_TYPE_TIME, mysql.MYSQL_TYPE_TIME2:
+		return "time"
+	case mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_DATETIME2:
+		return "datetime"
+	case mysql.MYSQL_TYPE_TIMESTAMP, mysql.MYSQL_TYPE_TIMESTAMP2:
+		return "timestamp"
+	case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING:
+		return "varchar"
+	case mysql.MYSQL_TYPE_STRING:
+		return "char"
+	case mysql.MYSQL_TYPE_TINY_BLOB, mysql.MYSQL_TYPE_MEDIUM_BLOB, mysql.MYSQL_TYPE_LONG_BLOB, mysql.MYSQL_TYPE_BLOB:
+		return "blob"
+	case mysql.MYSQL_TYPE_JSON:
+		return "json"
+	case mysql.MYSQL_TYPE_ENUM:
+		return "enum"
+	case mysql.MYSQL_TYPE_SET:
+		return "set"
+	case mysql.MYSQL_TYPE_GEOMETRY:
+		return "geometry"
+	default:
+		return "unknown"
+	}
+}
+
+func (m *tableMeta) describeColumns() []model.Column {
+	cols := make([]model.Column, len(m.columns))
+	for i, name := range m.columns {
+		if name == "" {
+			return nil
+		}
+		cols[i] = model.Column{Name: name, Type: "unknown", Nullable: true}
+		if i < len(m.columnTypes) {
+			cols[i].Type = m.columnTypes[i]
+			cols[i].Nullable = m.nullable[i]
+		}
+		if i < len(m.sqlTypes) {
+			cols[i].SQLType = m.sqlTypes[i]
+		}
+	}
+	return cols
+}
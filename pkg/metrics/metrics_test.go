@@ -0,0 +1,25 @@
+package metrics
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	r.Counter("reconnects").Inc()
+	r.Counter("reconnects").Add(2)
+	r.Gauge("buffered").Set(10)
+	r.Gauge("buffered").Add(-4)
+
+	snap := r.Snapshot()
+	if snap["reconnects"] != 3 {
+		t.Fatalf("expected reconnects=3, got %d", snap["reconnects"])
+	}
+	if snap["buffered"] != 6 {
+		t.Fatalf("expected buffered=6, got %d", snap["buffered"])
+	}
+
+	names := r.Names()
+	if len(names) != 2 || names[0] != "buffered" || names[1] != "reconnects" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+}
@@ -30,16 +30,18 @@ type BinlogDDLEvent struct {
 	source    SourceType
 	offsetVal MySQLOffset
 	txID      string
+	schema    string
 	query     string
 	timestamp time.Time
 }
 
-func NewBinlogDDLEvent(src SourceType, offset MySQLOffset, timestamp time.Time, txID, query string) *BinlogDDLEvent {
+func NewBinlogDDLEvent(src SourceType, offset MySQLOffset, timestamp time.Time, txID, schema, query string) *BinlogDDLEvent {
 	return &BinlogDDLEvent{
 		source:    src,
 		offsetVal: offset,
 		timestamp: timestamp,
 		txID:      txID,
+		schema:    schema,
 		query:     query,
 	}
 }
@@ -48,5 +50,6 @@ func (e *BinlogDDLEvent) Source() SourceType   { return e.source }
 func (e *BinlogDDLEvent) Offset() Offset       { return e.offsetVal }
 func (e *BinlogDDLEvent) Timestamp() time.Time { return e.timestamp }
 func (e *BinlogDDLEvent) TxID() string         { return e.txID }
+func (e *BinlogDDLEvent) Schema() string       { return e.schema }
 func (e *BinlogDDLEvent) Query() string        { return e.query }
 func (e *BinlogDDLEvent) Type() string         { return "ddl" }
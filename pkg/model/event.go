@@ -21,4 +21,11 @@ const (
 	OpInsert OpType = "INSERT"
 	OpUpdate OpType = "UPDATE"
 	OpDelete OpType = "DELETE"
+
+	// OpRead is a row read by a snapshot rather than inserted; it carries
+	// only After.
+	OpRead OpType = "READ"
+
+	// OpTruncate removes every row of the table; its RowChange has no rows.
+	OpTruncate OpType = "TRUNCATE"
 )
@@ -10,6 +10,11 @@ type Offset interface {
 type MySQLOffset struct {
 	File string `json:"file"`
 	Pos  uint32 `json:"pos"`
+
+	// GTIDSet is the executed GTID set up to and including this position. It
+	// is only set once the full set is known, and is preferred over File/Pos
+	// when resuming.
+	GTIDSet string `json:"gtid_set,omitempty"`
 }
 
 func (o MySQLOffset) Compare(other Offset) int {
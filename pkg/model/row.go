@@ -7,14 +7,52 @@ type RowChangeEvent interface {
 }
 
 type RowChange struct {
-	Schema string
-	Table  string
-	Op     OpType
-	Rows   []RowData
+	Schema  string    `json:"schema"`
+	Table   string    `json:"table"`
+	Op      OpType    `json:"op"`
+	Columns []Column  `json:"columns,omitempty"`
+	Rows    []RowData `json:"rows"`
+
+	// Statement is the SQL statement that produced the change, when the
+	// binlog records it (MySQL Rows_query, MariaDB ANNOTATE_ROWS).
+	Statement string `json:"statement,omitempty"`
+
+	// NonTransactional marks changes to tables whose engine cannot roll back
+	// (e.g. MyISAM); they survive a rollback of the enclosing transaction.
+	NonTransactional bool `json:"non_transactional,omitempty"`
+}
+
+// Column describes a captured table column as seen in the binlog table map.
+// Type is the lower-case MySQL type name (e.g. "bigint", "varchar"); SQLType
+// is the full column type (e.g. "varchar(255)", "int(10) unsigned") as far
+// as the table map describes it, which needs binlog_row_metadata=FULL for
+// lengths of character columns, signedness and enum values.
+type Column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	SQLType  string `json:"sql_type,omitempty"`
+	Nullable bool   `json:"nullable"`
 }
 
 type RowData struct {
 	PK     map[string]any `json:"pk,omitempty"`
 	Before map[string]any `json:"before,omitempty"`
 	After  map[string]any `json:"after,omitempty"`
+
+	// Diffs holds JSON columns logged as partial updates
+	// (binlog_row_value_options=PARTIAL_JSON); they are absent from After.
+	Diffs map[string]JSONDiff `json:"diffs,omitempty"`
+
+	// Partial reports that Before or After lack columns the binlog did not
+	// log (binlog_row_image=MINIMAL or NOBLOB, partial JSON updates). Absent
+	// columns are missing keys, while NULL columns are present with nil.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// JSONDiff is an in-place change of a JSON column: Op ("replace", "insert"
+// or "remove") applied at Path with Value as JSON text.
+type JSONDiff struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value,omitempty"`
 }
@@ -8,14 +8,39 @@ const (
 	TxBegin    TxBoundaryKind = "BEGIN"
 	TxCommit   TxBoundaryKind = "COMMIT"
 	TxRollback TxBoundaryKind = "ROLLBACK"
+
+	// TxAbort discards a transaction that was cut short in the stream, e.g.
+	// by a reconnect; unlike TxRollback nothing of it took effect.
+	TxAbort TxBoundaryKind = "ABORT"
+
+	// TxSavepoint and TxRollbackTo carry the savepoint name. Changes to
+	// transactional tables after the savepoint are undone by TxRollbackTo.
+	TxSavepoint  TxBoundaryKind = "SAVEPOINT"
+	TxRollbackTo TxBoundaryKind = "ROLLBACK TO"
 )
 
+// TxMeta describes the origin of a committed transaction.
+type TxMeta struct {
+	// GTID is "uuid:gno" on MySQL and "domain-server-seq" on MariaDB, empty
+	// when the server does not assign GTIDs.
+	GTID string `json:"gtid,omitempty"`
+	// XID is the storage engine transaction ID, zero for transactions
+	// committed without one (DDL, non-transactional engines).
+	XID uint64 `json:"xid,omitempty"`
+	// ServerID is the server the transaction originated on, which differs
+	// from the one we read from behind a replica.
+	ServerID   uint32    `json:"server_id"`
+	CommitTime time.Time `json:"commit_time"`
+}
+
 type TransactionBoundaryEvent struct {
 	source    SourceType
 	offset    Offset
 	timestamp time.Time
 	txID      string
 	kind      TxBoundaryKind
+	savepoint string
+	meta      TxMeta
 }
 
 func NewTransactionBoundaryEvent(source SourceType, offset Offset, timestamp time.Time, txID string, kind TxBoundaryKind) *TransactionBoundaryEvent {
@@ -28,11 +53,36 @@ func NewTransactionBoundaryEvent(source SourceType, offset Offset, timestamp tim
 	}
 }
 
+func NewSavepointEvent(source SourceType, offset Offset, timestamp time.Time, txID string, kind TxBoundaryKind, savepoint string) *TransactionBoundaryEvent {
+	e := NewTransactionBoundaryEvent(source, offset, timestamp, txID, kind)
+	e.savepoint = savepoint
+	return e
+}
+
+// NewTransactionEndEvent builds the COMMIT or ROLLBACK boundary of a
+// transaction along with its origin.
+func NewTransactionEndEvent(source SourceType, offset Offset, timestamp time.Time, txID string, kind TxBoundaryKind, meta TxMeta) *TransactionBoundaryEvent {
+	e := NewTransactionBoundaryEvent(source, offset, timestamp, txID, kind)
+	e.meta = meta
+	return e
+}
+
 func (e *TransactionBoundaryEvent) Source() SourceType   { return e.source }
 func (e *TransactionBoundaryEvent) Offset() Offset       { return e.offset }
 func (e *TransactionBoundaryEvent) Timestamp() time.Time { return e.timestamp }
 func (e *TransactionBoundaryEvent) TxID() string         { return e.txID }
 func (e *TransactionBoundaryEvent) Kind() TxBoundaryKind { return e.kind }
+func (e *TransactionBoundaryEvent) Savepoint() string    { return e.savepoint }
+func (e *TransactionBoundaryEvent) Meta() TxMeta         { return e.meta }
+
+// Fragment places a TransactionEvent within a transaction that was published
+// in parts. Parts share the txID and are published in Index order.
+type Fragment struct {
+	Index int `json:"index"`
+	// RowOffset is the number of rows in the preceding fragments.
+	RowOffset int  `json:"row_offset"`
+	Last      bool `json:"last"`
+}
 
 type TransactionEvent struct {
 	source    SourceType
@@ -40,20 +90,41 @@ type TransactionEvent struct {
 	timestamp time.Time
 	txID      string
 	changes   []RowChange
+	meta      TxMeta
+	fragment  *Fragment
 }
 
-func NewTransactionEvent(source SourceType, offset Offset, timestamp time.Time, txID string, changes []RowChange) *TransactionEvent {
+func NewTransactionEvent(source SourceType, offset Offset, timestamp time.Time, txID string, changes []RowChange, meta TxMeta) *TransactionEvent {
 	return &TransactionEvent{
 		source:    source,
 		offset:    offset,
 		timestamp: timestamp,
 		txID:      txID,
 		changes:   changes,
+		meta:      meta,
 	}
 }
 
+// NewTransactionFragment builds one part of a transaction published in
+// fragments. Only the last fragment should carry the commit offset.
+func NewTransactionFragment(source SourceType, offset Offset, timestamp time.Time, txID string, changes []RowChange, meta TxMeta, fragment Fragment) *TransactionEvent {
+	e := NewTransactionEvent(source, offset, timestamp, txID, changes, meta)
+	e.fragment = &fragment
+	return e
+}
+
 func (e *TransactionEvent) Source() SourceType   { return e.source }
 func (e *TransactionEvent) Offset() Offset       { return e.offset }
 func (e *TransactionEvent) Timestamp() time.Time { return e.timestamp }
 func (e *TransactionEvent) TxID() string         { return e.txID }
 func (e *TransactionEvent) Changes() []RowChange { return e.changes }
+func (e *TransactionEvent) Meta() TxMeta         { return e.meta }
+
+// Fragment reports the event's place in a fragmented transaction, if it is
+// one.
+func (e *TransactionEvent) Fragment() (Fragment, bool) {
+	if e.fragment == nil {
+		return Fragment{}, false
+	}
+	return *e.fragment, true
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	// WarningBinlogPurged reports that changes between the last committed
+	// offset and the new start position were lost.
+	WarningBinlogPurged = "binlog_purged"
+	// WarningXARollback reports that an XA transaction, published when it
+	// was prepared, was rolled back afterwards.
+	WarningXARollback = "xa_rollback"
+)
+
+// WarningEvent tells consumers about a condition in the change stream itself,
+// such as a gap, rather than a change to the data.
+type WarningEvent struct {
+	source    SourceType
+	offset    Offset
+	timestamp time.Time
+	code      string
+	message   string
+}
+
+func NewWarningEvent(source SourceType, offset Offset, timestamp time.Time, code, message string) *WarningEvent {
+	return &WarningEvent{
+		source:    source,
+		offset:    offset,
+		timestamp: timestamp,
+		code:      code,
+		message:   message,
+	}
+}
+
+func (e *WarningEvent) Source() SourceType   { return e.source }
+func (e *WarningEvent) Offset() Offset       { return e.offset }
+func (e *WarningEvent) Timestamp() time.Time { return e.timestamp }
+func (e *WarningEvent) Code() string         { return e.code }
+func (e *WarningEvent) Message() string      { return e.message }
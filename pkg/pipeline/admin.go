@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler serves the admin API:
+//
+//	GET /pipelines         status of every pipeline
+//	GET /pipelines/{name}  status of one pipeline
+//	GET /healthz           200 when every pipeline is running, 503 otherwise
+func Handler(pipelines []*Pipeline) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /pipelines", func(w http.ResponseWriter, r *http.Request) {
+		statuses := make([]Status, 0, len(pipelines))
+		for _, p := range pipelines {
+			statuses = append(statuses, p.Status())
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	})
+
+	mux.HandleFunc("GET /pipelines/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range pipelines {
+			if p.Name() == r.PathValue("name") {
+				writeJSON(w, http.StatusOK, p.Status())
+				return
+			}
+		}
+		http.Error(w, "unknown pipeline", http.StatusNotFound)
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		unhealthy := map[string]State{}
+		for _, p := range pipelines {
+			if st := p.Status(); st.State != StateRunning {
+				unhealthy[st.Name] = st.State
+			}
+		}
+		if len(unhealthy) > 0 {
+			writeJSON(w, http.StatusServiceUnavailable, unhealthy)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
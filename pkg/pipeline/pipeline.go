@@ -0,0 +1,316 @@
+// Package pipeline runs the pipelines of a cdc-server process side by side:
+// each has its own database connection, source, offsets and metrics, and is
+// restarted on its own when it fails, unless the error needs operator action.
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/bootstrap"
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
+	"github.com/cursus-io/tabellarius/pkg/source"
+	"github.com/cursus-io/tabellarius/pkg/util"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	// StateFailed is a pipeline waiting to restart after an error, or
+	// stopped for good after a fatal one (Status.Fatal).
+	StateFailed  State = "failed"
+	StateStopped State = "stopped"
+)
+
+// Status is a point-in-time view of a pipeline for the admin API. Fatal
+// marks a failure that needs operator action, after which the pipeline is
+// not restarted.
+type Status struct {
+	Name     string           `json:"name"`
+	State    State            `json:"state"`
+	Database string           `json:"database"`
+	Since    time.Time        `json:"since"`
+	Restarts int              `json:"restarts"`
+	Error    string           `json:"error,omitempty"`
+	Fatal    bool             `json:"fatal,omitempty"`
+	Metrics  map[string]int64 `json:"metrics"`
+}
+
+// Pipeline supervises the source of one pipeline config.
+type Pipeline struct {
+	name    string
+	metrics *metrics.Registry
+
+	// run connects and streams until ctx is cancelled or the pipeline
+	// fails, calling ready once it is streaming.
+	run        func(ctx context.Context, ready func()) error
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// reloadMu serializes reloads; mu guards the fields below it.
+	reloadMu sync.Mutex
+	mu       sync.Mutex
+	cfg      *config.Config
+	status   Status
+	src      *source.TabellariusSource
+}
+
+func New(cfg *config.Config) *Pipeline {
+	p := &Pipeline{
+		name:       cfg.Name,
+		cfg:        cfg,
+		metrics:    metrics.NewRegistry(),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		status: Status{
+			Name:     cfg.Name,
+			State:    StateStarting,
+			Database: cfg.MaskedDSN(),
+			Since:    time.Now(),
+		},
+	}
+	p.run = p.runSource
+	return p
+}
+
+// Name never changes, as renaming a pipeline needs a restart.
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+func (p *Pipeline) config() *config.Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cfg
+}
+
+// Reload switches the pipeline to cfg when the changes can be applied live
+// and refuses them with a config.UnsafeChangeError otherwise. A pipeline
+// that is not streaming picks cfg up when it restarts.
+func (p *Pipeline) Reload(cfg *config.Config) (config.Changes, error) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	p.mu.Lock()
+	cur, src := p.cfg, p.src
+	p.mu.Unlock()
+
+	ch, err := config.Diff(cur, cfg)
+	if err != nil || ch.Empty() {
+		return ch, err
+	}
+	if src != nil {
+		if err := src.Reload(cfg, ch); err != nil {
+			return ch, err
+		}
+	}
+
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+	return ch, nil
+}
+
+// Run keeps the pipeline running until ctx is cancelled, restarting it with
+// capped exponential backoff whenever it fails. A fatal error (see
+// inspector.IsFatal), such as bad credentials or a purged binlog with
+// database.on_purged: fail, leaves it failed and is returned.
+func (p *Pipeline) Run(ctx context.Context) error {
+	backoff := p.minBackoff
+
+	for {
+		p.set(StateStarting, nil)
+		started := time.Now()
+		err := p.run(ctx, func() { p.set(StateRunning, nil) })
+		if ctx.Err() != nil {
+			p.set(StateStopped, nil)
+			return nil
+		}
+		if err == nil {
+			err = errors.New("stopped unexpectedly")
+		}
+
+		if inspector.IsFatal(err) {
+			log.Printf("[pipeline] %s failed, not restarting: %v", p.Name(), err)
+			p.set(StateFailed, err)
+			p.mu.Lock()
+			p.status.Fatal = true
+			p.mu.Unlock()
+			return err
+		}
+
+		// a pipeline that ran for a while failed afresh
+		if time.Since(started) > p.maxBackoff {
+			backoff = p.minBackoff
+		}
+		log.Printf("[pipeline] %s failed, restarting in %v: %v", p.Name(), backoff, err)
+		p.set(StateFailed, err)
+
+		select {
+		case <-ctx.Done():
+			p.set(StateStopped, nil)
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, p.maxBackoff)
+
+		p.mu.Lock()
+		p.status.Restarts++
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pipeline) set(state State, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status.State != state {
+		p.status.Since = time.Now()
+	}
+	p.status.State = state
+	p.status.Error = ""
+	if err != nil {
+		p.status.Error = err.Error()
+	}
+}
+
+// attach makes src, started from cfg, the target of reloads and catches it
+// up with any reload that arrived while it was starting.
+func (p *Pipeline) attach(src *source.TabellariusSource, cfg *config.Config) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	p.mu.Lock()
+	p.src = src
+	latest := p.cfg
+	p.mu.Unlock()
+
+	if ch, err := config.Diff(cfg, latest); err == nil && !ch.Empty() {
+		if err := src.Reload(latest, ch); err != nil {
+			log.Printf("[reload] %s: %v", p.Name(), err)
+		}
+	}
+}
+
+func (p *Pipeline) detach() {
+	p.mu.Lock()
+	p.src = nil
+	p.mu.Unlock()
+}
+
+// Status reports the pipeline's state and metrics.
+func (p *Pipeline) Status() Status {
+	p.mu.Lock()
+	st := p.status
+	p.mu.Unlock()
+
+	st.Metrics = p.metrics.Snapshot()
+	return st
+}
+
+// runSource connects to the database and runs a source until it fails.
+func (p *Pipeline) runSource(ctx context.Context, ready func()) error {
+	cfg := p.config()
+
+	// held while running so cdc-cli does not move the checkpoint meanwhile
+	lock, err := util.LockFile(source.LockPath(cfg.CDCServer.OffsetFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(cfg.Database.Type.DriverName(), dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("db connection failed: %w", err)
+	}
+
+	ok, err := bootstrap.Inspect(db, cfg)
+	if err != nil {
+		return fmt.Errorf("inspect failed: %w", err)
+	}
+	if !ok {
+		return errors.New("cdc_log table not found. bootstrap required")
+	}
+
+	src, err := source.New(db, cfg, p.metrics)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	src.Start(ctx)
+	log.Printf("[pipeline] %s started", p.Name())
+	p.attach(src, cfg)
+	defer p.detach()
+	ready()
+
+	select {
+	case <-ctx.Done():
+		<-src.Done()
+		return ctx.Err()
+	case err := <-src.Err():
+		cancel()
+		<-src.Done()
+		return err
+	}
+}
+
+// Reload applies srv to the running pipelines, each on its own: one whose
+// changes are unsafe keeps running as before without affecting the others.
+// Pipelines added to or removed from srv need a restart.
+func Reload(pipelines []*Pipeline, srv *config.Server) {
+	next := map[string]*config.Config{}
+	for i := range srv.Pipelines {
+		next[srv.Pipelines[i].Name] = &srv.Pipelines[i]
+	}
+
+	for _, p := range pipelines {
+		cfg, ok := next[p.Name()]
+		if !ok {
+			log.Printf("[reload] %s: removed from the config, restart required", p.Name())
+			continue
+		}
+		delete(next, p.Name())
+
+		ch, err := p.Reload(cfg)
+		switch {
+		case err != nil:
+			log.Printf("[reload] %s: refused: %v", p.Name(), err)
+		case ch.Empty():
+			log.Printf("[reload] %s: no changes", p.Name())
+		default:
+			log.Printf("[reload] %s: applied %s", p.Name(), ch)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(next)) {
+		log.Printf("[reload] %s: new pipeline, restart required", name)
+	}
+}
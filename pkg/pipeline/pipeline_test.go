@@ -0,0 +1,177 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/go-mysql-org/go-mysql/mysql"
+)
+
+func testPipeline(name string, run func(ctx context.Context, ready func()) error) *Pipeline {
+	p := New(&config.Config{
+		Name:     name,
+		Database: config.Database{Type: model.MySQL, Schema: "shop", User: "root", Password: "secret", Host: "db", Port: 3306},
+	})
+	p.run = run
+	p.minBackoff = time.Millisecond
+	p.maxBackoff = 5 * time.Millisecond
+	return p
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRun_FailureIsIsolated(t *testing.T) {
+	healthy := testPipeline("orders", func(ctx context.Context, ready func()) error {
+		ready()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	failing := testPipeline("billing", func(ctx context.Context, ready func()) error {
+		return errors.New("binlog purged")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, p := range []*Pipeline{healthy, failing} {
+		wg.Go(func() { p.Run(ctx) })
+	}
+
+	waitFor(t, func() bool { return failing.Status().Restarts >= 3 })
+	if st := healthy.Status(); st.State != StateRunning || st.Restarts != 0 {
+		t.Fatalf("healthy pipeline affected: %+v", st)
+	}
+	if st := failing.Status(); st.Error != "binlog purged" || st.Database != "root:******@tcp(db:3306)/shop?parseTime=true" {
+		t.Fatalf("unexpected failing status: %+v", st)
+	}
+
+	cancel()
+	wg.Wait()
+	for _, p := range []*Pipeline{healthy, failing} {
+		if st := p.Status(); st.State != StateStopped {
+			t.Fatalf("%s: expected stopped, got %s", st.Name, st.State)
+		}
+	}
+}
+
+func TestRun_FatalIsNotRetried(t *testing.T) {
+	denied := &mysql.MyError{Code: mysql.ER_ACCESS_DENIED_ERROR, Message: "Access denied for user 'cdc'"}
+	runs := 0
+	p := testPipeline("orders", func(ctx context.Context, ready func()) error {
+		runs++
+		return fmt.Errorf("binlog stream: %w", denied)
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- p.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, denied) {
+			t.Fatalf("expected the fatal error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pipeline kept restarting after a fatal error")
+	}
+	if st := p.Status(); runs != 1 || st.Restarts != 0 || st.State != StateFailed || !st.Fatal {
+		t.Fatalf("expected one run and a fatal failed state, got %d runs: %+v", runs, st)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	running := testPipeline("orders", nil)
+	running.set(StateRunning, nil)
+	running.metrics.Counter("source_events_total").Add(42)
+	failed := testPipeline("billing", nil)
+	failed.set(StateFailed, errors.New("access denied"))
+
+	srv := httptest.NewServer(Handler([]*Pipeline{running, failed}))
+	defer srv.Close()
+
+	get := func(path string, out any) int {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var all []Status
+	if code := get("/pipelines", &all); code != http.StatusOK || len(all) != 2 || all[0].Name != "orders" {
+		t.Fatalf("unexpected /pipelines: %d %+v", code, all)
+	}
+	if all[0].Metrics["source_events_total"] != 42 || all[1].Metrics["source_events_total"] != 0 {
+		t.Fatalf("metrics not kept per pipeline: %+v", all)
+	}
+
+	var one Status
+	if code := get("/pipelines/billing", &one); code != http.StatusOK || one.State != StateFailed || one.Error != "access denied" {
+		t.Fatalf("unexpected /pipelines/billing: %d %+v", code, one)
+	}
+	if code := get("/pipelines/nope", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", code)
+	}
+
+	var unhealthy map[string]State
+	if code := get("/healthz", &unhealthy); code != http.StatusServiceUnavailable || unhealthy["billing"] != StateFailed {
+		t.Fatalf("unexpected /healthz: %d %v", code, unhealthy)
+	}
+	failed.set(StateRunning, nil)
+	if code := get("/healthz", nil); code != http.StatusOK {
+		t.Fatalf("expected healthy, got %d", code)
+	}
+}
+
+func TestReload(t *testing.T) {
+	p := testPipeline("orders", nil)
+	cur := p.config()
+
+	next := *cur
+	next.Tables = []config.Table{{Name: "orders", PK: "id"}}
+	ch, err := p.Reload(&next)
+	if err != nil || len(ch.AddedTables) != 1 {
+		t.Fatalf("safe change refused: %v %v", ch, err)
+	}
+	if p.config() != &next {
+		t.Fatal("pipeline does not restart with the reloaded config")
+	}
+
+	unsafe := next
+	unsafe.Database.Host = "replica"
+	unsafe.Tables = nil
+	var uerr config.UnsafeChangeError
+	if _, err := p.Reload(&unsafe); !errors.As(err, &uerr) || p.config() != &next {
+		t.Fatalf("unsafe change not refused: %v", err)
+	}
+
+	// pipelines added or removed are left alone
+	other := next
+	other.Name = "billing"
+	Reload([]*Pipeline{p}, &config.Server{Pipelines: []config.Config{other}})
+	if p.config() != &next {
+		t.Fatal("removed pipeline reconfigured")
+	}
+}
@@ -2,38 +2,165 @@ package source
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/format"
 	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
 	"github.com/cursus-io/tabellarius/pkg/model"
 	"github.com/cursus-io/tabellarius/pkg/source/cursus"
+	"github.com/cursus-io/tabellarius/pkg/source/file"
+	"github.com/cursus-io/tabellarius/pkg/source/webhook"
 	"github.com/cursus-io/tabellarius/pkg/util"
 )
 
+// NewFromConfig is New with the default metrics registry, exiting on error.
 func NewFromConfig(db *sql.DB, cfg *config.Config) *TabellariusSource {
+	src, err := New(db, cfg, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return src
+}
+
+// New builds the source of one pipeline. Its metrics go to reg, or to
+// metrics.Default when reg is nil.
+func New(db *sql.DB, cfg *config.Config, reg *metrics.Registry) (*TabellariusSource, error) {
+	pub, err := NewPublisher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	spillDir := cfg.CDCServer.Buffer.SpillDir
+	if spillDir == "" && cfg.Name != "" {
+		// a buffer clears stale segments from its directory on start, so
+		// pipelines must not share one
+		spillDir = filepath.Join(os.TempDir(), "tabellarius-spill", cfg.Name)
+	}
+
 	switch cfg.Database.Type {
 	case model.MySQL, model.MariaDB:
-		return NewMySQLSource(db, cfg.Database.Type, cfg.Database.Schema, cfg.DSN(), cfg.CDCServer.OffsetFile, cfg.CDCServer.PublisherAddr, cfg.Tables)
+		dsn, err := cfg.DSN()
+		if err != nil {
+			return nil, err
+		}
+		return NewMySQLSource(db, cfg.Database.Type, cfg.Database.Schema, dsn, cfg.CDCServer.OffsetFile, pub, cfg.Tables, inspector.Options{
+			OnPurged:   cfg.Database.OnPurged,
+			Statements: cfg.Database.Statements,
+			Metrics:    reg,
+		}, Options{
+			Buffer: BufferOptions{
+				MaxMemoryBytes: cfg.CDCServer.Buffer.MaxMemoryBytes,
+				SpillDir:       spillDir,
+			},
+			Fragment: FragmentOptions{
+				MaxRows:  cfg.CDCServer.Fragment.MaxRows,
+				MaxBytes: cfg.CDCServer.Fragment.MaxBytes,
+			},
+			Metrics: reg,
+		})
 	case model.Postgres:
-		log.Fatal("postgres source not implemented")
+		return nil, fmt.Errorf("postgres source not implemented")
 	default:
-		log.Fatalf("unsupported database type: %s", cfg.Database.Type)
+		return nil, fmt.Errorf("unsupported database type: %s", cfg.Database.Type)
 	}
-	return nil
 }
 
-func NewMySQLSource(db *sql.DB, dbType model.DatabaseType, dbSchema, dbDSN string, offsetPath string, pubAddr string, tables []config.Table) *TabellariusSource {
-	binlogOffset := offsetPath + ".binlog"
-	ins, err := inspector.NewBinlogInspector(db, dbType, dbSchema, dbDSN, binlogOffset, util.GenerateID(), tables)
-	if err != nil {
-		log.Fatal(err)
+func NewPublisher(cfg *config.Config) (Publisher, error) {
+	switch cfg.Sink.Type {
+	case "", config.SinkCursus:
+		if cfg.Sink.Format == format.CloudEvents && cfg.Sink.CloudEventsMode == format.CloudEventsBinary {
+			return nil, fmt.Errorf("cloudevents binary mode needs message headers, which the cursus sink does not support")
+		}
+		enc, err := newEncoder(cfg)
+		if err != nil {
+			return nil, err
+		}
+		pub := cursus.NewCursusPublisher(cfg.CDCServer.PublisherAddr, enc)
+		if pub == nil {
+			return nil, fmt.Errorf("failed to create cursus publisher")
+		}
+		return pub, nil
+
+	case config.SinkHTTP:
+		if cfg.Sink.HTTP.URL == "" {
+			return nil, fmt.Errorf("http sink requires sink.http.url")
+		}
+		enc, err := newEncoder(cfg)
+		if err != nil {
+			return nil, err
+		}
+		hc := cfg.Sink.HTTP
+		return webhook.NewPublisher(hc.URL, hc.Headers, hc.Timeout, enc), nil
+
+	case config.SinkFile:
+		fc := cfg.Sink.File
+		var store file.Storage
+		switch {
+		case fc.S3.Bucket != "":
+			s3 := fc.S3
+			store = file.NewS3Storage(s3.Endpoint, s3.Region, s3.Bucket, s3.Prefix, s3.AccessKey, s3.SecretKey.Value())
+		case fc.Path != "":
+			store = file.NewLocalStorage(fc.Path)
+		default:
+			return nil, fmt.Errorf("file sink requires sink.file.path or sink.file.s3.bucket")
+		}
+		return file.NewSink(store, file.Options{MaxFileBytes: fc.MaxFileBytes, RollInterval: fc.RollInterval}), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", cfg.Sink.Type)
 	}
+}
 
-	var inspector inspector.Inspector[model.Event] = ins
+func newEncoder(cfg *config.Config) (format.Encoder, error) {
+	return format.New(cfg.Sink.Format, format.Options{
+		ServerName:      cfg.Sink.ServerName,
+		RegistryURL:     cfg.Sink.SchemaRegistry.URL,
+		CloudEventsMode: cfg.Sink.CloudEventsMode,
+		CanalTxMarkers:  cfg.Sink.CanalTxMarkers,
+	})
+}
 
+// NewWithInspector builds a source reading from ins, such as binlog files
+// decoded offline, that does not persist offsets.
+func NewWithInspector(ins inspector.Inspector[model.Event], pub Publisher, opts Options) *TabellariusSource {
 	return &TabellariusSource{
-		ins: inspector,
-		pub: cursus.NewCursusPublisher(pubAddr),
+		ins:  ins,
+		pub:  pub,
+		opts: opts,
+		errc: make(chan error, 1),
+		swap: make(chan Publisher),
+	}
+}
+
+// BinlogOffsetPath is the checkpoint file of a binlog source configured with
+// cdc_server.offset_file offsetFile.
+func BinlogOffsetPath(offsetFile string) string {
+	return offsetFile + ".binlog"
+}
+
+// LockPath is the file a running pipeline keeps locked, so its checkpoint is
+// not edited underneath it.
+func LockPath(offsetFile string) string {
+	return offsetFile + ".lock"
+}
+
+func NewMySQLSource(db *sql.DB, dbType model.DatabaseType, dbSchema, dbDSN string, offsetPath string, pub Publisher, tables []config.Table, insOpts inspector.Options, opts Options) (*TabellariusSource, error) {
+	// without an offsetPath the source streams without persisting offsets
+	binlogOffset := ""
+	if offsetPath != "" {
+		binlogOffset = BinlogOffsetPath(offsetPath)
 	}
+	ins, err := inspector.NewBinlogInspector(db, dbType, dbSchema, dbDSN, binlogOffset, util.GenerateID(), tables, insOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	src := NewWithInspector(ins, pub, opts)
+	src.offsetPath = binlogOffset
+	return src, nil
 }
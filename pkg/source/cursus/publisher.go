@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/cursus-io/tabellarius/pkg/format"
 	"github.com/cursus-io/tabellarius/pkg/model"
 	"github.com/downfa11-org/cursus/test/publisher/config" // todo. updated cursus package
 	"github.com/downfa11-org/cursus/test/publisher/producer"
@@ -13,9 +14,10 @@ import (
 
 type Publisher struct {
 	pub *producer.Publisher
+	enc format.Encoder
 }
 
-func NewCursusPublisher(addr string) *Publisher {
+func NewCursusPublisher(addr string, enc format.Encoder) *Publisher {
 	cfg, err := config.LoadPublisherConfig() // "/config.yaml"
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
@@ -30,6 +32,7 @@ func NewCursusPublisher(addr string) *Publisher {
 
 	return &Publisher{
 		pub: pub,
+		enc: enc,
 	}
 }
 
@@ -48,6 +51,9 @@ func (p *Publisher) Publish(evt model.Event) error {
 	case model.RowChangeEvent:
 		changes := e.Changes()
 		for ci, change := range changes {
+			if change.Op == model.OpTruncate {
+				log.Printf("%s [row][%d] table=%s.%s txID=%s op=TRUNCATE", prefix, ci, change.Schema, change.Table, e.TxID())
+			}
 			for ri, row := range change.Rows {
 				if change.Op == model.OpUpdate && row.Before != nil && row.After != nil {
 					beforeJSON, err := json.Marshal(row.Before)
@@ -72,14 +78,19 @@ func (p *Publisher) Publish(evt model.Event) error {
 		log.Printf("%s [unknown event]", prefix)
 	}
 
-	eventJSON, err := json.Marshal(evt)
+	msgs, err := p.enc.Encode(evt)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+		return fmt.Errorf("failed to encode event: %w", err)
 	}
 
-	_, err = p.pub.PublishMessage(string(eventJSON))
-	if err != nil {
-		return fmt.Errorf("failed to publish message: %w", err)
+	for _, msg := range msgs {
+		// cursus messages are unkeyed, so tombstones carry no information
+		if msg.Value == nil {
+			continue
+		}
+		if _, err := p.pub.PublishMessage(string(msg.Value)); err != nil {
+			return fmt.Errorf("failed to publish message: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,335 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Minimal Parquet writer: one row group per file, one PLAIN encoded data page
+// per column, no compression. Every column is a flat OPTIONAL field, so only
+// definition levels (max level 1) are written.
+
+var parquetMagic = []byte("PAR1")
+
+// parquet.thrift enum values
+const (
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+
+	convertedUTF8            int32 = 0
+	convertedTimestampMillis int32 = 9
+	convertedTimestampMicros int32 = 10
+
+	repetitionOptional int32 = 1
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	pageTypeData int32 = 0
+)
+
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32 // -1 when the column has no converted type
+}
+
+// value converts v to the column's physical type, reporting false for a
+// null. Values the column cannot hold are an error rather than a silent
+// null or wrap-around.
+func (c parquetColumn) value(v any) (any, bool, error) {
+	if v == nil {
+		return nil, false, nil
+	}
+
+	switch c.physical {
+	case typeInt64:
+		switch x := v.(type) {
+		case time.Time:
+			if c.converted == convertedTimestampMillis {
+				return x.UnixMilli(), true, nil
+			}
+			return x.UnixMicro(), true, nil
+		case int:
+			return int64(x), true, nil
+		case int8:
+			return int64(x), true, nil
+		case int16:
+			return int64(x), true, nil
+		case int32:
+			return int64(x), true, nil
+		case int64:
+			return x, true, nil
+		case uint8:
+			return int64(x), true, nil
+		case uint16:
+			return int64(x), true, nil
+		case uint32:
+			return int64(x), true, nil
+		case uint64:
+			if x > math.MaxInt64 {
+				return nil, false, fmt.Errorf("column %s: %d overflows INT64", c.name, x)
+			}
+			return int64(x), true, nil
+		}
+		return nil, false, nil
+
+	case typeDouble:
+		switch x := v.(type) {
+		case float32:
+			return float64(x), true, nil
+		case float64:
+			return x, true, nil
+		}
+		return nil, false, nil
+
+	default:
+		switch x := v.(type) {
+		case []byte:
+			return x, true, nil
+		case string:
+			return []byte(x), true, nil
+		case time.Time:
+			return []byte(x.Format(time.RFC3339Nano)), true, nil
+		default:
+			return []byte(fmt.Sprint(x)), true, nil
+		}
+	}
+}
+
+func writeParquet(w io.Writer, cols []parquetColumn, rows [][]any) error {
+	var body bytes.Buffer
+	body.Write(parquetMagic)
+
+	chunks := make([]columnChunk, len(cols))
+	var totalSize int64
+
+	for i, col := range cols {
+		page, err := encodePage(col, i, rows)
+		if err != nil {
+			return err
+		}
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.beginField(5, thriftStruct)
+		header.beginStruct()
+		header.i32(1, int32(len(rows)))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		offset := int64(body.Len())
+		body.Write(header.Bytes())
+		body.Write(page)
+
+		size := int64(header.Len() + len(page))
+		chunks[i] = columnChunk{offset: offset, size: size}
+		totalSize += size
+	}
+
+	footer := encodeFooter(cols, chunks, int64(len(rows)), totalSize)
+	body.Write(footer)
+
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(footer)))
+	body.Write(n[:])
+	body.Write(parquetMagic)
+
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+func encodePage(col parquetColumn, idx int, rows [][]any) ([]byte, error) {
+	levels := make([]byte, len(rows))
+	var values bytes.Buffer
+
+	for r, row := range rows {
+		v, ok, err := col.value(row[idx])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		levels[r] = 1
+
+		switch x := v.(type) {
+		case int64:
+			_ = binary.Write(&values, binary.LittleEndian, x)
+		case float64:
+			_ = binary.Write(&values, binary.LittleEndian, math.Float64bits(x))
+		case []byte:
+			_ = binary.Write(&values, binary.LittleEndian, uint32(len(x)))
+			values.Write(x)
+		}
+	}
+
+	rle := encodeLevels(levels)
+
+	var page bytes.Buffer
+	_ = binary.Write(&page, binary.LittleEndian, uint32(len(rle)))
+	page.Write(rle)
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
+
+// encodeLevels writes bit width 1 definition levels as plain RLE runs.
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+func encodeFooter(cols []parquetColumn, chunks []columnChunk, numRows, totalSize int64) []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, 1)
+
+	t.beginField(2, thriftList)
+	t.listHeader(thriftStruct, len(cols)+1)
+	t.beginStruct()
+	t.str(4, "schema")
+	t.i32(5, int32(len(cols)))
+	t.endStruct()
+	for _, col := range cols {
+		t.beginStruct()
+		t.i32(1, col.physical)
+		t.i32(3, repetitionOptional)
+		t.str(4, col.name)
+		if col.converted >= 0 {
+			t.i32(6, col.converted)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, numRows)
+
+	t.beginField(4, thriftList)
+	t.listHeader(thriftStruct, 1)
+	t.beginStruct()
+	t.beginField(1, thriftList)
+	t.listHeader(thriftStruct, len(cols))
+	for i, col := range cols {
+		t.beginStruct()
+		t.i64(2, chunks[i].offset)
+		t.beginField(3, thriftStruct)
+		t.beginStruct()
+		t.i32(1, col.physical)
+		t.beginField(2, thriftList)
+		t.listHeader(thriftI32, 2)
+		t.varint(int64(encodingPlain))
+		t.varint(int64(encodingRLE))
+		t.beginField(3, thriftList)
+		t.listHeader(thriftBinary, 1)
+		t.bytes([]byte(col.name))
+		t.i32(4, 0)
+		t.i64(5, numRows)
+		t.i64(6, chunks[i].size)
+		t.i64(7, chunks[i].size)
+		t.i64(9, chunks[i].offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64(2, totalSize)
+	t.i64(3, numRows)
+	t.endStruct()
+
+	t.str(6, "tabellarius")
+	t.endStruct()
+
+	return t.Bytes()
+}
+
+// thrift compact protocol field types
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+type thriftWriter struct {
+	bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.WriteByte(0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) beginField(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) listHeader(elem byte, size int) {
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	t.WriteByte(0xF0 | elem)
+	t.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+// varint writes a zigzag encoded integer.
+func (t *thriftWriter) varint(v int64) {
+	t.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (t *thriftWriter) bytes(b []byte) {
+	t.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	t.Write(b)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.beginField(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.beginField(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.beginField(id, thriftBinary)
+	t.bytes([]byte(s))
+}
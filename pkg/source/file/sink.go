@@ -0,0 +1,374 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+const (
+	DefaultMaxFileBytes = 64 << 20
+	DefaultRollInterval = 5 * time.Minute
+)
+
+// CDC metadata columns prepended to every file.
+var metaColumns = []parquetColumn{
+	{name: "_op", physical: typeByteArray, converted: convertedUTF8},
+	{name: "_tx_id", physical: typeByteArray, converted: convertedUTF8},
+	{name: "_offset", physical: typeByteArray, converted: convertedUTF8},
+	{name: "_commit_time", physical: typeInt64, converted: convertedTimestampMillis},
+}
+
+type Options struct {
+	MaxFileBytes int64
+	RollInterval time.Duration
+}
+
+// Sink buffers row changes per table and hour and writes them as Parquet files.
+// Offsets are acknowledged through the commit callback only once every row up
+// to that offset is part of a finalized file.
+type Sink struct {
+	mu      sync.Mutex
+	store   Storage
+	opts    Options
+	buffers map[string]*buffer
+	pending []model.Offset
+	commit  func(model.Offset)
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type buffer struct {
+	columns []model.Column
+	schema  []parquetColumn
+	rows    [][]any
+	size    int64
+	opened  time.Time
+	first   model.Offset
+}
+
+func NewSink(store Storage, opts Options) *Sink {
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if opts.RollInterval <= 0 {
+		opts.RollInterval = DefaultRollInterval
+	}
+
+	s := &Sink{
+		store:   store,
+		opts:    opts,
+		buffers: make(map[string]*buffer),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go s.rollLoop()
+	return s
+}
+
+func (s *Sink) OnCommit(fn func(model.Offset)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commit = fn
+}
+
+func (s *Sink) Publish(evt model.Event) error {
+	e, ok := evt.(model.RowChangeEvent)
+	if !ok {
+		return nil
+	}
+
+	hour := evt.Timestamp().UTC().Format("2006-01-02-15")
+	changes := make([]staged, 0, len(e.Changes()))
+	layouts := map[string][]model.Column{}
+	for _, change := range e.Changes() {
+		columns := change.Columns
+		if len(columns) == 0 {
+			columns = inferColumns(change.Rows)
+		}
+		st := staged{
+			key:     fmt.Sprintf("%s.%s/hour=%s", change.Schema, change.Table, hour),
+			columns: columns,
+			op:      change.Op,
+		}
+		if change.Op == model.OpTruncate {
+			st.images = append(st.images, nil)
+		}
+		for _, row := range change.Rows {
+			image := row.After
+			if change.Op == model.OpDelete {
+				image = row.Before
+			}
+			st.images = append(st.images, image)
+		}
+		if err := st.check(); err != nil {
+			return fmt.Errorf("%s.%s: %w", change.Schema, change.Table, err)
+		}
+		// a table's layout cannot change inside a transaction, since DDL
+		// commits implicitly
+		if prev, ok := layouts[st.key]; ok && !sameColumns(prev, st.columns) {
+			return fmt.Errorf("%s.%s: columns changed within transaction %s", change.Schema, change.Table, e.TxID())
+		}
+		layouts[st.key] = st.columns
+		changes = append(changes, st)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// close files whose layout changed before buffering anything, so an
+	// error leaves no rows of evt behind to be written twice on a retry
+	for _, st := range changes {
+		if buf, ok := s.buffers[st.key]; ok && !sameColumns(buf.columns, st.columns) {
+			if err := s.finalize(st.key); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, st := range changes {
+		buf, ok := s.buffers[st.key]
+		if !ok {
+			buf = newBuffer(st.columns, evt.Offset())
+			s.buffers[st.key] = buf
+		}
+		for _, image := range st.images {
+			buf.append(st.op, e.TxID(), evt.Offset(), evt.Timestamp(), image)
+		}
+	}
+	s.pending = append(s.pending, evt.Offset())
+
+	// evt is buffered now; a failed roll is retried by the roll loop
+	for _, st := range changes {
+		if buf, ok := s.buffers[st.key]; ok && buf.size >= s.opts.MaxFileBytes {
+			if err := s.finalize(st.key); err != nil {
+				log.Printf("[file] failed to roll %s: %v", st.key, err)
+			}
+		}
+	}
+
+	s.advance()
+	return nil
+}
+
+// staged is one change of an event, checked before it is buffered.
+type staged struct {
+	key     string
+	columns []model.Column
+	op      model.OpType
+	images  []map[string]any
+}
+
+// check reports values the change's columns cannot be written as.
+func (st staged) check() error {
+	for _, c := range st.columns {
+		col := parquetColumnFor(c)
+		for _, image := range st.images {
+			if _, _, err := col.value(image[c.Name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Flush finalizes every open file regardless of size or age.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys() {
+		if err := s.finalize(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.Flush()
+}
+
+func (s *Sink) rollLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.RollInterval / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for _, key := range s.keys() {
+				buf := s.buffers[key]
+				if now.Sub(buf.opened) < s.opts.RollInterval && buf.size < s.opts.MaxFileBytes {
+					continue
+				}
+				if err := s.finalize(key); err != nil {
+					log.Printf("[file] failed to roll %s: %v", key, err)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Sink) keys() []string {
+	keys := make([]string, 0, len(s.buffers))
+	for k := range s.buffers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// finalize writes the buffer for key and removes it. Callers must hold s.mu.
+func (s *Sink) finalize(key string) error {
+	buf := s.buffers[key]
+
+	var data bytes.Buffer
+	if err := writeParquet(&data, buf.schema, buf.rows); err != nil {
+		return fmt.Errorf("encode parquet %s: %w", key, err)
+	}
+
+	name := fmt.Sprintf("%s/part-%s.parquet", key, strings.ReplaceAll(buf.first.String(), ":", "-"))
+	if err := s.store.Put(context.Background(), name, data.Bytes()); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+
+	log.Printf("[file] finalized %s rows=%d bytes=%d", name, len(buf.rows), data.Len())
+	delete(s.buffers, key)
+	s.advance()
+	return nil
+}
+
+// advance commits the newest published offset that no open buffer depends on.
+func (s *Sink) advance() {
+	var oldest model.Offset
+	for _, buf := range s.buffers {
+		if oldest == nil || buf.first.Compare(oldest) < 0 {
+			oldest = buf.first
+		}
+	}
+
+	var committed model.Offset
+	for len(s.pending) > 0 {
+		if oldest != nil && s.pending[0].Compare(oldest) >= 0 {
+			break
+		}
+		committed = s.pending[0]
+		s.pending = s.pending[1:]
+	}
+
+	if committed != nil && s.commit != nil {
+		s.commit(committed)
+	}
+}
+
+func newBuffer(columns []model.Column, first model.Offset) *buffer {
+	schema := append([]parquetColumn(nil), metaColumns...)
+	for _, c := range columns {
+		schema = append(schema, parquetColumnFor(c))
+	}
+
+	return &buffer{
+		columns: columns,
+		schema:  schema,
+		opened:  time.Now(),
+		first:   first,
+	}
+}
+
+func (b *buffer) append(op model.OpType, txID string, offset model.Offset, ts time.Time, image map[string]any) {
+	row := make([]any, 0, len(b.schema))
+	row = append(row, string(op), txID, offset.String(), ts)
+	b.size += int64(len(op) + len(txID) + len(offset.String()) + 8)
+
+	for _, c := range b.columns {
+		v := image[c.Name]
+		row = append(row, v)
+		b.size += valueSize(v)
+	}
+
+	b.rows = append(b.rows, row)
+}
+
+func parquetColumnFor(c model.Column) parquetColumn {
+	switch c.Type {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "year", "bit", "enum", "set":
+		return parquetColumn{name: c.Name, physical: typeInt64, converted: -1}
+	case "float", "double":
+		return parquetColumn{name: c.Name, physical: typeDouble, converted: -1}
+	case "datetime", "timestamp":
+		return parquetColumn{name: c.Name, physical: typeInt64, converted: convertedTimestampMicros}
+	case "blob", "geometry":
+		return parquetColumn{name: c.Name, physical: typeByteArray, converted: -1}
+	default:
+		return parquetColumn{name: c.Name, physical: typeByteArray, converted: convertedUTF8}
+	}
+}
+
+// inferColumns is used when the binlog carried no column metadata; every
+// column is written as a string.
+func inferColumns(rows []model.RowData) []model.Column {
+	seen := map[string]bool{}
+	for _, r := range rows {
+		for k := range r.After {
+			seen[k] = true
+		}
+		for k := range r.Before {
+			seen[k] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	cols := make([]model.Column, len(names))
+	for i, n := range names {
+		cols[i] = model.Column{Name: n, Type: "unknown", Nullable: true}
+	}
+	return cols
+}
+
+func sameColumns(a, b []model.Column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Type != b[i].Type {
+			return false
+		}
+	}
+	return true
+}
+
+func valueSize(v any) int64 {
+	switch x := v.(type) {
+	case nil:
+		return 1
+	case string:
+		return int64(len(x)) + 4
+	case []byte:
+		return int64(len(x)) + 4
+	default:
+		return 8
+	}
+}
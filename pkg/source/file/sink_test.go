@@ -0,0 +1,311 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/parquet-go/parquet-go"
+)
+
+func newTx(pos uint32, table string, id int64) *model.TransactionEvent {
+	offset := model.MySQLOffset{File: "binlog.000001", Pos: pos}
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	return model.NewTransactionEvent(model.SourceMySQLBinlog, offset, ts, "tx-1", []model.RowChange{
+		{
+			Schema: "test",
+			Table:  table,
+			Op:     model.OpInsert,
+			Columns: []model.Column{
+				{Name: "id", Type: "bigint"},
+				{Name: "name", Type: "varchar", Nullable: true},
+			},
+			Rows: []model.RowData{
+				{After: map[string]any{"id": id, "name": "alice"}},
+			},
+		},
+	}, model.TxMeta{})
+}
+
+func TestSink_CommitAfterFinalize(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSink(NewLocalStorage(dir), Options{RollInterval: time.Hour})
+	defer s.Close()
+
+	var committed []model.Offset
+	s.OnCommit(func(o model.Offset) { committed = append(committed, o) })
+
+	if err := s.Publish(newTx(100, "users", 1)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := s.Publish(newTx(200, "orders", 2)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(committed) != 0 {
+		t.Fatalf("offset committed before file finalized: %v", committed)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if len(committed) != 1 || committed[0].String() != "binlog.000001:200" {
+		t.Fatalf("unexpected commits: %v", committed)
+	}
+
+	path := filepath.Join(dir, "test.users", "hour=2026-01-02-15", "part-binlog.000001-100.parquet")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("parquet file missing: %v", err)
+	}
+	if !bytes.HasPrefix(b, parquetMagic) || !bytes.HasSuffix(b, parquetMagic) {
+		t.Fatal("invalid parquet magic")
+	}
+
+	footerLen := binary.LittleEndian.Uint32(b[len(b)-8:])
+	if int(footerLen) >= len(b) {
+		t.Fatalf("invalid footer length: %d", footerLen)
+	}
+}
+
+func TestSink_RollBySize(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSink(NewLocalStorage(dir), Options{MaxFileBytes: 1, RollInterval: time.Hour})
+	defer s.Close()
+
+	var committed model.Offset
+	s.OnCommit(func(o model.Offset) { committed = o })
+
+	if err := s.Publish(newTx(100, "users", 1)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if committed == nil || committed.String() != "binlog.000001:100" {
+		t.Fatalf("expected commit after size roll, got %v", committed)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test.users", "*", "*.parquet"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+}
+
+func TestSink_CommitWaitsForOldestBuffer(t *testing.T) {
+	s := NewSink(NewLocalStorage(t.TempDir()), Options{RollInterval: time.Hour})
+	defer s.Close()
+
+	var committed model.Offset
+	s.OnCommit(func(o model.Offset) { committed = o })
+
+	_ = s.Publish(newTx(100, "users", 1))
+	_ = s.Publish(newTx(200, "orders", 2))
+
+	s.mu.Lock()
+	err := s.finalize("test.orders/hour=2026-01-02-15")
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatalf("finalize failed: %v", err)
+	}
+	if committed != nil {
+		t.Fatalf("users buffer still open, but committed %v", committed)
+	}
+}
+
+// readParquet decodes a file written by the sink with an independent reader,
+// returning its column names and rows.
+func readParquet(t *testing.T, b []byte) ([]string, []parquet.Row) {
+	t.Helper()
+
+	f, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+
+	var rows []parquet.Row
+	for _, rg := range f.RowGroups() {
+		r := rg.Rows()
+		buf := make([]parquet.Row, rg.NumRows())
+		n, err := r.ReadRows(buf)
+		if err != nil && err != io.EOF {
+			t.Fatalf("read rows: %v", err)
+		}
+		rows = append(rows, buf[:n]...)
+		r.Close()
+	}
+	if int64(len(rows)) != f.NumRows() {
+		t.Fatalf("read %d rows, footer says %d", len(rows), f.NumRows())
+	}
+	return names, rows
+}
+
+func TestWriteParquet_RoundTrip(t *testing.T) {
+	created := time.Date(2026, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	cols := append(append([]parquetColumn(nil), metaColumns...),
+		parquetColumnFor(model.Column{Name: "id", Type: "bigint"}),
+		parquetColumnFor(model.Column{Name: "name", Type: "varchar"}),
+		parquetColumnFor(model.Column{Name: "score", Type: "double"}),
+		parquetColumnFor(model.Column{Name: "created", Type: "datetime"}),
+	)
+	rows := [][]any{
+		{"c", "tx-1", "binlog.000001:100", created, int64(1), "alice", 1.5, created},
+		{"c", "tx-1", "binlog.000001:100", created, uint64(2), nil, nil, nil},
+	}
+
+	var b bytes.Buffer
+	if err := writeParquet(&b, cols, rows); err != nil {
+		t.Fatal(err)
+	}
+	names, got := readParquet(t, b.Bytes())
+
+	wantNames := []string{"_op", "_tx_id", "_offset", "_commit_time", "id", "name", "score", "created"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("expected columns %v, got %v", wantNames, names)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(got))
+	}
+
+	first := got[0]
+	if string(first[0].ByteArray()) != "c" || string(first[2].ByteArray()) != "binlog.000001:100" {
+		t.Fatalf("unexpected metadata %v", first)
+	}
+	if first[3].Int64() != created.UnixMilli() || first[7].Int64() != created.UnixMicro() {
+		t.Fatalf("unexpected timestamps %v", first)
+	}
+	if first[4].Int64() != 1 || string(first[5].ByteArray()) != "alice" || first[6].Double() != 1.5 {
+		t.Fatalf("unexpected values %v", first)
+	}
+
+	second := got[1]
+	if second[4].Int64() != 2 || !second[5].IsNull() || !second[6].IsNull() || !second[7].IsNull() {
+		t.Fatalf("unexpected values %v", second)
+	}
+}
+
+func TestSink_RejectsUnsignedOverflow(t *testing.T) {
+	s := NewSink(NewLocalStorage(t.TempDir()), Options{RollInterval: time.Hour})
+	defer s.Close()
+
+	tx := newTx(100, "users", 1)
+	tx.Changes()[0].Rows[0].After["id"] = uint64(math.MaxUint64)
+	if err := s.Publish(tx); err == nil {
+		t.Fatal("expected an error for a value beyond INT64")
+	}
+	if len(s.buffers) != 0 {
+		t.Fatalf("rejected event was buffered: %v", s.buffers)
+	}
+}
+
+// flakyStorage fails the first Put.
+type flakyStorage struct {
+	Storage
+	failed bool
+}
+
+func (s *flakyStorage) Put(ctx context.Context, name string, data []byte) error {
+	if !s.failed {
+		s.failed = true
+		return errors.New("unavailable")
+	}
+	return s.Storage.Put(ctx, name, data)
+}
+
+func TestSink_RetryAfterFailedFinalize(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSink(&flakyStorage{Storage: NewLocalStorage(dir)}, Options{RollInterval: time.Hour})
+	defer s.Close()
+
+	if err := s.Publish(newTx(100, "users", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new column closes the open file first, and that write fails
+	altered := newTx(200, "users", 2)
+	altered.Changes()[0].Columns = append(altered.Changes()[0].Columns, model.Column{Name: "age", Type: "int"})
+	if err := s.Publish(altered); err == nil {
+		t.Fatal("expected the failed finalize to be reported")
+	}
+	if err := s.Publish(altered); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test.users", "*", "*.parquet"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, rows := readParquet(t, b); len(rows) != 1 {
+			t.Fatalf("%s: expected 1 row, got %d", path, len(rows))
+		}
+	}
+}
+
+// countingStorage records the names it is given.
+type countingStorage struct {
+	Storage
+	names []string
+}
+
+func (s *countingStorage) Put(ctx context.Context, name string, data []byte) error {
+	s.names = append(s.names, name)
+	return s.Storage.Put(ctx, name, data)
+}
+
+func TestSink_LayoutChangeFinalizesOnce(t *testing.T) {
+	store := &countingStorage{Storage: NewLocalStorage(t.TempDir())}
+	s := NewSink(store, Options{RollInterval: time.Hour})
+	defer s.Close()
+
+	if err := s.Publish(newTx(100, "users", 1)); err != nil {
+		t.Fatal(err)
+	}
+	altered := newTx(200, "users", 2)
+	altered.Changes()[0].Columns = append(altered.Changes()[0].Columns, model.Column{Name: "age", Type: "int"})
+	if err := s.Publish(altered); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.names) != 1 {
+		t.Fatalf("expected the old file finalized once, got %v", store.names)
+	}
+
+	mixed := newTx(300, "users", 3)
+	mixed.Changes()[0].Columns = altered.Changes()[0].Columns
+	mixed.Changes()[0].Rows[0].After["age"] = int32(30)
+	other := newTx(300, "users", 4).Changes()[0]
+	mixed = model.NewTransactionEvent(mixed.Source(), mixed.Offset(), mixed.Timestamp(), mixed.TxID(), []model.RowChange{mixed.Changes()[0], other}, model.TxMeta{})
+	if err := s.Publish(mixed); err == nil {
+		t.Fatal("expected a layout change within one transaction to be rejected")
+	}
+	if len(store.names) != 1 {
+		t.Fatalf("rejected event finalized files: %v", store.names)
+	}
+}
+
+func TestSink_CloseTwice(t *testing.T) {
+	s := NewSink(NewLocalStorage(t.TempDir()), Options{RollInterval: time.Hour})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,93 @@
+package source
+
+import (
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// FragmentOptions splits transactions with more than MaxRows rows or more
+// than MaxBytes (estimated) into ordered fragments. Zero disables a limit.
+type FragmentOptions struct {
+	MaxRows  int
+	MaxBytes int64
+}
+
+func (o FragmentOptions) full(rows int, bytes int64) bool {
+	return (o.MaxRows > 0 && rows >= o.MaxRows) || (o.MaxBytes > 0 && bytes >= o.MaxBytes)
+}
+
+// fragmenter cuts a stream of changes into fragments. A full fragment is held
+// back until the next row arrives, so the final one can be flagged as last;
+// a transaction that never fills a fragment is published whole (frag nil).
+type fragmenter struct {
+	opts    FragmentOptions
+	publish func(changes []model.RowChange, frag *model.Fragment) error
+
+	cur      []model.RowChange
+	curRows  int
+	curBytes int64
+
+	pending     []model.RowChange
+	pendingRows int
+
+	index     int
+	rowOffset int
+}
+
+func (f *fragmenter) add(c model.RowChange) error {
+	if len(c.Rows) == 0 {
+		f.cur = append(f.cur, c)
+		return nil
+	}
+
+	split := true
+	for _, row := range c.Rows {
+		if f.opts.full(f.curRows, f.curBytes) {
+			if err := f.rotate(); err != nil {
+				return err
+			}
+			split = true
+		}
+
+		if split {
+			part := c
+			part.Rows = nil
+			f.cur = append(f.cur, part)
+			split = false
+		}
+
+		last := &f.cur[len(f.cur)-1]
+		last.Rows = append(last.Rows, row)
+		f.curRows++
+		f.curBytes += mapSize(row.PK) + mapSize(row.Before) + mapSize(row.After)
+	}
+	return nil
+}
+
+// rotate publishes the held back fragment and holds back the current one.
+func (f *fragmenter) rotate() error {
+	if f.pending != nil {
+		if err := f.publish(f.pending, &model.Fragment{Index: f.index, RowOffset: f.rowOffset}); err != nil {
+			return err
+		}
+		f.index++
+		f.rowOffset += f.pendingRows
+	}
+
+	f.pending, f.pendingRows = f.cur, f.curRows
+	f.cur, f.curRows, f.curBytes = nil, 0, 0
+	return nil
+}
+
+func (f *fragmenter) finish() error {
+	if f.pending == nil {
+		if len(f.cur) == 0 {
+			return nil
+		}
+		return f.publish(f.cur, nil)
+	}
+
+	if err := f.publish(f.pending, &model.Fragment{Index: f.index, RowOffset: f.rowOffset}); err != nil {
+		return err
+	}
+	return f.publish(f.cur, &model.Fragment{Index: f.index + 1, RowOffset: f.rowOffset + f.pendingRows, Last: true})
+}
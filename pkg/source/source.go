@@ -2,38 +2,157 @@ package source
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"time"
 
+	"github.com/cursus-io/tabellarius/pkg/config"
 	"github.com/cursus-io/tabellarius/pkg/inspector"
+	"github.com/cursus-io/tabellarius/pkg/metrics"
 	"github.com/cursus-io/tabellarius/pkg/model"
-	"github.com/cursus-io/tabellarius/pkg/source/cursus"
+	"github.com/cursus-io/tabellarius/pkg/util"
 )
 
+// Publisher delivers events to a sink. Publishers that also implement
+// AsyncPublisher acknowledge offsets themselves; for all others the offset is
+// committed as soon as Publish returns without error.
+type Publisher interface {
+	Publish(evt model.Event) error
+}
+
+// AsyncPublisher is implemented by sinks that make data durable after Publish
+// returns (e.g. the file sink finalizing a Parquet file).
+type AsyncPublisher interface {
+	Publisher
+	OnCommit(fn func(model.Offset))
+}
+
 type TabellariusSource struct {
-	ins inspector.Inspector[model.Event]
-	pub *cursus.Publisher
+	ins        inspector.Inspector[model.Event]
+	pub        Publisher
+	offsetPath string
+	opts       Options
+
+	errc chan error
+	done chan struct{}
+	// swap hands a reloaded publisher to the run loop.
+	swap chan Publisher
+}
+
+// reloader is implemented by inspectors that can change their tables while
+// running.
+type reloader interface {
+	Reload(u inspector.Update)
 }
 
 func (s *TabellariusSource) Start(ctx context.Context) {
 	ch := make(chan model.Event, 128)
 
+	async := false
+	if p, ok := s.pub.(AsyncPublisher); ok {
+		p.OnCommit(s.commit)
+		async = true
+	}
+
 	go func() {
 		defer close(ch)
-		_ = s.ins.Start(ctx, ch)
+		if err := s.ins.Start(ctx, ch); err != nil {
+			s.errc <- err
+		}
+	}()
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx, ch, async)
 	}()
+}
+
+// Done is closed once the source has stopped and closed its publisher. It
+// is only valid after Start.
+func (s *TabellariusSource) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err delivers the error that stopped the inspector, if any. The source stops
+// after sending it.
+func (s *TabellariusSource) Err() <-chan error {
+	return s.errc
+}
+
+// Reload applies live config changes. A sink change swaps the publisher
+// between transactions, closing the old one once it has flushed; table and
+// statement changes go to the inspector. If the new sink cannot be built
+// nothing changes.
+func (s *TabellariusSource) Reload(cfg *config.Config, ch config.Changes) error {
+	r, ok := s.ins.(reloader)
+	if !ok && (ch.Tables() || ch.Statements) {
+		return errors.New("inspector cannot change tables while running")
+	}
+
+	if ch.Sink {
+		pub, err := NewPublisher(cfg)
+		if err != nil {
+			return fmt.Errorf("sink: %w", err)
+		}
+		select {
+		case s.swap <- pub:
+		case <-s.done:
+			closePublisher(pub)
+			return errors.New("source stopped")
+		}
+	}
+
+	if ch.Tables() || ch.Statements {
+		var snapshot []string
+		for _, t := range ch.AddedTables {
+			if t.Snapshot {
+				snapshot = append(snapshot, t.Name)
+			}
+		}
+		r.Reload(inspector.Update{Tables: cfg.Tables, Snapshot: snapshot, Statements: cfg.Database.Statements})
+	}
+	return nil
+}
+
+func closePublisher(pub Publisher) {
+	if c, ok := pub.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("[run] failed to close publisher: %v", err)
+		}
+	}
+}
 
-	go s.run(ctx, ch)
+func (s *TabellariusSource) commit(offset model.Offset) {
+	if s.offsetPath == "" {
+		return
+	}
+	if err := util.SaveJSON(s.offsetPath, offset); err != nil {
+		log.Printf("[source] failed to save offset: %v", err)
+	}
 }
 
-func (s *TabellariusSource) run(ctx context.Context, in <-chan model.Event) {
-	txBuffer := map[string][]model.RowChange{}
+func (s *TabellariusSource) run(ctx context.Context, in <-chan model.Event, async bool) {
+	reg := s.opts.Metrics
+	if reg == nil {
+		reg = metrics.Default
+	}
+	events := reg.Counter("source_events_total")
+	lagMillis := reg.Gauge("source_lag_ms")
+
+	txBuffer := newTxBuffer(s.opts.Buffer, reg)
+	savepoints := map[string]map[string]int{}
+	beginOffsets := map[string]model.Offset{}
 	var lastOffset model.Offset
 	var lastSource model.SourceType
 	var eventCount uint64
 
 	defer func() {
-		log.Printf("Shutting down. Remaining transactions in buffer: %d", len(txBuffer))
+		log.Printf("Shutting down. Remaining transactions in buffer: %d", txBuffer.Count())
+		txBuffer.Close()
+		closePublisher(s.pub)
 	}()
 
 	for {
@@ -42,6 +161,17 @@ func (s *TabellariusSource) run(ctx context.Context, in <-chan model.Event) {
 			log.Println("Context cancelled, stopping...")
 			return
 
+		case pub := <-s.swap:
+			// open transactions are published to the new sink on commit
+			closePublisher(s.pub)
+			s.pub = pub
+			async = false
+			if p, ok := pub.(AsyncPublisher); ok {
+				p.OnCommit(s.commit)
+				async = true
+			}
+			log.Printf("[source] sink reloaded")
+
 		case evt, ok := <-in:
 			if !ok {
 				log.Println("Input channel closed, exiting loop")
@@ -50,10 +180,14 @@ func (s *TabellariusSource) run(ctx context.Context, in <-chan model.Event) {
 
 			lag := time.Since(evt.Timestamp())
 			eventCount++
+			events.Inc()
+			lagMillis.Set(lag.Milliseconds())
 
 			// Log metrics periodically (every 1000 events)
 			if eventCount%1000 == 0 {
-				log.Printf("[metrics] Processed: %d, Current Lag: %v", eventCount, lag)
+				snap := reg.Snapshot()
+				log.Printf("[metrics] Processed: %d, Current Lag: %v, Reconnects: %d, Buffered: %d bytes (%d on disk)",
+					eventCount, lag, snap["binlog_reconnects_total"], snap["txbuffer_memory_bytes"], snap["txbuffer_disk_bytes"])
 			}
 
 			lastOffset = evt.Offset()
@@ -61,30 +195,105 @@ func (s *TabellariusSource) run(ctx context.Context, in <-chan model.Event) {
 
 			switch e := evt.(type) {
 			case model.RowChangeEvent:
-				txBuffer[e.TxID()] = append(txBuffer[e.TxID()], e.Changes()...)
+				if err := txBuffer.Append(e.TxID(), e.Changes()); err != nil {
+					log.Printf("[run] failed to spill TxID %s, keeping it in memory: %v", e.TxID(), err)
+				}
 			case *model.BinlogDDLEvent:
 				log.Printf("[schema] DDL Detected: %s (Offset: %v)", e.Query(), lastOffset)
 				_ = s.pub.Publish(e)
+			case *model.WarningEvent:
+				log.Printf("[source] WARN %s: %s (Offset: %v)", e.Code(), e.Message(), lastOffset)
+				if err := s.pub.Publish(e); err != nil {
+					log.Printf("[run] Publish error for warning %s: %v", e.Code(), err)
+				}
 			case *model.TransactionBoundaryEvent:
+				txID := e.TxID()
 				switch e.Kind() {
-				case model.TxCommit:
-					// On Commit, bundle all buffered changes into a single transaction
-					changes := txBuffer[e.TxID()]
-					if len(changes) == 0 {
-						delete(txBuffer, e.TxID())
-						continue
+				case model.TxBegin:
+					beginOffsets[txID] = lastOffset
+
+				case model.TxCommit, model.TxRollback:
+					err := s.publishTx(txBuffer, e, lastSource, lastOffset, beginOffsets[txID])
+					delete(savepoints, txID)
+					delete(beginOffsets, txID)
+					if err != nil {
+						log.Printf("[run] Publish error for TxID %s: %v", txID, err)
+					} else if !async {
+						s.commit(lastOffset)
 					}
 
-					txEvt := model.NewTransactionEvent(lastSource, lastOffset, e.Timestamp(), e.TxID(), changes)
-					if err := s.pub.Publish(txEvt); err != nil {
-						log.Printf("[run] Publish error for TxID %s: %v", e.TxID(), err)
+				case model.TxAbort:
+					txBuffer.Discard(txID)
+					delete(savepoints, txID)
+					delete(beginOffsets, txID)
+
+				case model.TxSavepoint:
+					if savepoints[txID] == nil {
+						savepoints[txID] = map[string]int{}
 					}
-					delete(txBuffer, e.TxID())
+					savepoints[txID][e.Savepoint()] = txBuffer.Len(txID)
 
-				case model.TxRollback:
-					delete(txBuffer, e.TxID())
+				case model.TxRollbackTo:
+					at, ok := savepoints[txID][e.Savepoint()]
+					if !ok {
+						log.Printf("[run] unknown savepoint %s in TxID %s", e.Savepoint(), txID)
+						continue
+					}
+					keep := func(c model.RowChange) bool { return c.NonTransactional }
+					if err := txBuffer.Truncate(txID, at, keep); err != nil {
+						log.Printf("[run] failed to roll back TxID %s to %s: %v", txID, e.Savepoint(), err)
+					}
+
+					// later savepoints are released by the rollback
+					for name, pos := range savepoints[txID] {
+						if pos > at {
+							delete(savepoints[txID], name)
+						}
+					}
 				}
 			}
 		}
 	}
 }
+
+// publishTx publishes the buffered changes of a finished transaction: all of
+// them on commit, only those of non-transactional tables on rollback. Large
+// transactions are published in fragments; all but the last carry the resume
+// point of the BEGIN boundary, the previous commit, so a sink acknowledging
+// them cannot commit past a transaction it has only partly received. Without
+// a BEGIN boundary the transaction is published whole.
+func (s *TabellariusSource) publishTx(buf *txBuffer, e *model.TransactionBoundaryEvent, src model.SourceType, offset, begin model.Offset) error {
+	txID := e.TxID()
+	defer buf.Discard(txID)
+
+	opts := s.opts.Fragment
+	if begin == nil {
+		opts = FragmentOptions{}
+	}
+
+	f := &fragmenter{
+		opts: opts,
+		publish: func(changes []model.RowChange, frag *model.Fragment) error {
+			if frag == nil {
+				return s.pub.Publish(model.NewTransactionEvent(src, offset, e.Timestamp(), txID, changes, e.Meta()))
+			}
+
+			off := begin
+			if frag.Last {
+				off = offset
+			}
+			return s.pub.Publish(model.NewTransactionFragment(src, off, e.Timestamp(), txID, changes, e.Meta(), *frag))
+		},
+	}
+
+	err := buf.Each(txID, func(c model.RowChange) error {
+		if e.Kind() == model.TxRollback && !c.NonTransactional {
+			return nil
+		}
+		return f.add(c)
+	})
+	if err != nil {
+		return err
+	}
+	return f.finish()
+}
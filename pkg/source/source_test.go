@@ -1,10 +1,16 @@
 package source
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/cursus-io/tabellarius/pkg/config"
+	"github.com/cursus-io/tabellarius/pkg/format"
 	"github.com/cursus-io/tabellarius/pkg/model"
+	"github.com/cursus-io/tabellarius/pkg/util"
 )
 
 func TestNewFromConfig_MySQL(t *testing.T) {
@@ -24,3 +30,373 @@ func TestNewFromConfig_MySQL(t *testing.T) {
 		t.Fatal("expected source, got nil")
 	}
 }
+
+type recordingPublisher struct {
+	published []*model.TransactionEvent
+}
+
+func (p *recordingPublisher) Publish(evt model.Event) error {
+	if tx, ok := evt.(*model.TransactionEvent); ok {
+		p.published = append(p.published, tx)
+	}
+	return nil
+}
+
+func TestRun_RollbackKeepsNonTransactional(t *testing.T) {
+	off := model.MySQLOffset{File: "binlog.000001", Pos: 100}
+	now := time.Now()
+	rows := func(table string, nonTx bool) model.Event {
+		return model.NewBinlogRowEvent(model.SourceMySQLBinlog, off, now, "tx:1", []model.RowChange{{
+			Schema: "shop", Table: table, Op: model.OpInsert, NonTransactional: nonTx,
+		}})
+	}
+	boundary := func(kind model.TxBoundaryKind) model.Event {
+		return model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, off, now, "tx:1", kind)
+	}
+	savepoint := func(kind model.TxBoundaryKind) model.Event {
+		return model.NewSavepointEvent(model.SourceMySQLBinlog, off, now, "tx:1", kind, "sp1")
+	}
+
+	tests := []struct {
+		name   string
+		events []model.Event
+		want   []string
+	}{
+		{
+			name:   "commit",
+			events: []model.Event{boundary(model.TxBegin), rows("orders", false), rows("audit", true), boundary(model.TxCommit)},
+			want:   []string{"orders", "audit"},
+		},
+		{
+			name:   "rollback",
+			events: []model.Event{boundary(model.TxBegin), rows("orders", false), rows("audit", true), boundary(model.TxRollback)},
+			want:   []string{"audit"},
+		},
+		{
+			name:   "abort",
+			events: []model.Event{boundary(model.TxBegin), rows("orders", false), rows("audit", true), boundary(model.TxAbort)},
+			want:   nil,
+		},
+		{
+			name: "rollback to savepoint",
+			events: []model.Event{
+				boundary(model.TxBegin),
+				rows("orders", false),
+				savepoint(model.TxSavepoint),
+				rows("items", false),
+				rows("audit", true),
+				savepoint(model.TxRollbackTo),
+				rows("payments", false),
+				boundary(model.TxCommit),
+			},
+			want: []string{"orders", "audit", "payments"},
+		},
+	}
+
+	for _, tt := range tests {
+		pub := &recordingPublisher{}
+		s := &TabellariusSource{pub: pub}
+
+		in := make(chan model.Event, len(tt.events))
+		for _, evt := range tt.events {
+			in <- evt
+		}
+		close(in)
+		s.run(context.Background(), in, false)
+
+		var got []string
+		for _, tx := range pub.published {
+			for _, c := range tx.Changes() {
+				got = append(got, c.Table)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+type failingPublisher struct {
+	recordingPublisher
+	failAt int
+}
+
+func (p *failingPublisher) Publish(evt model.Event) error {
+	if len(p.published) == p.failAt {
+		return fmt.Errorf("publish %d refused", p.failAt)
+	}
+	return p.recordingPublisher.Publish(evt)
+}
+
+func TestRun_Fragments(t *testing.T) {
+	begin := model.MySQLOffset{File: "binlog.000001", Pos: 100}
+	end := model.MySQLOffset{File: "binlog.000001", Pos: 900}
+	now := time.Now()
+
+	row := func(id int) model.RowData {
+		return model.RowData{PK: map[string]any{"id": id}, After: map[string]any{"id": id}}
+	}
+	events := []model.Event{
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, begin, now, "tx:1", model.TxBegin),
+		model.NewBinlogRowEvent(model.SourceMySQLBinlog, begin, now, "tx:1", []model.RowChange{{
+			Schema: "shop", Table: "orders", Op: model.OpInsert, Rows: []model.RowData{row(1), row(2), row(3)},
+		}}),
+		model.NewBinlogRowEvent(model.SourceMySQLBinlog, begin, now, "tx:1", []model.RowChange{{
+			Schema: "shop", Table: "items", Op: model.OpInsert, Rows: []model.RowData{row(4), row(5)},
+		}}),
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, end, now, "tx:1", model.TxCommit),
+	}
+
+	tests := []struct {
+		name    string
+		maxRows int
+		failAt  int
+		want    []string
+		commit  bool
+	}{
+		{name: "whole", maxRows: 0, failAt: -1, want: []string{"-:5@binlog.000001:900"}, commit: true},
+		{
+			name: "fragmented", maxRows: 2, failAt: -1,
+			want: []string{
+				"0/0:2@binlog.000001:100",
+				"1/2:2@binlog.000001:100",
+				"2/4 last:1@binlog.000001:900",
+			},
+			commit: true,
+		},
+		{
+			name: "failed fragment", maxRows: 2, failAt: 1,
+			want: []string{"0/0:2@binlog.000001:100"},
+		},
+	}
+
+	for _, tt := range tests {
+		offsetPath := t.TempDir() + "/offset.json"
+		pub := &failingPublisher{failAt: tt.failAt}
+		s := &TabellariusSource{pub: pub, offsetPath: offsetPath, opts: Options{Fragment: FragmentOptions{MaxRows: tt.maxRows}}}
+
+		in := make(chan model.Event, len(events))
+		for _, evt := range events {
+			in <- evt
+		}
+		close(in)
+		s.run(context.Background(), in, false)
+
+		var got []string
+		for _, tx := range pub.published {
+			rows := 0
+			for _, c := range tx.Changes() {
+				rows += len(c.Rows)
+			}
+			desc := "-"
+			if f, ok := tx.Fragment(); ok {
+				desc = fmt.Sprintf("%d/%d", f.Index, f.RowOffset)
+				if f.Last {
+					desc += " last"
+				}
+			}
+			got = append(got, fmt.Sprintf("%s:%d@%s", desc, rows, tx.Offset()))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+
+		_, err := os.Stat(offsetPath)
+		if committed := err == nil; committed != tt.commit {
+			t.Fatalf("%s: expected committed=%v, got %v", tt.name, tt.commit, committed)
+		}
+	}
+}
+
+// TestRun_FragmentEventIDs publishes a fragmented transaction right after a
+// small one; its first fragments carry the small one's commit offset.
+func TestRun_FragmentEventIDs(t *testing.T) {
+	first := model.MySQLOffset{File: "binlog.000001", Pos: 100}
+	end := model.MySQLOffset{File: "binlog.000001", Pos: 900}
+	now := time.Now()
+
+	row := func(id int) model.RowData {
+		return model.RowData{PK: map[string]any{"id": id}, After: map[string]any{"id": id}}
+	}
+	in := make(chan model.Event, 8)
+	for _, evt := range []model.Event{
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, first, now, "tx:1", model.TxBegin),
+		model.NewBinlogRowEvent(model.SourceMySQLBinlog, first, now, "tx:1", []model.RowChange{{
+			Schema: "shop", Table: "orders", Op: model.OpInsert, Rows: []model.RowData{row(1), row(2)},
+		}}),
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, first, now, "tx:1", model.TxCommit),
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, first, now, "tx:2", model.TxBegin),
+		model.NewBinlogRowEvent(model.SourceMySQLBinlog, end, now, "tx:2", []model.RowChange{{
+			Schema: "shop", Table: "orders", Op: model.OpInsert, Rows: []model.RowData{row(3), row(4), row(5), row(6), row(7)},
+		}}),
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, end, now, "tx:2", model.TxCommit),
+	} {
+		in <- evt
+	}
+	close(in)
+
+	pub := &recordingPublisher{}
+	s := &TabellariusSource{pub: pub, offsetPath: t.TempDir() + "/offset.json", opts: Options{Fragment: FragmentOptions{MaxRows: 2}}}
+	s.run(context.Background(), in, false)
+
+	enc, _ := format.NewCloudEventsEncoder("dbserver1", format.CloudEventsBinary)
+	seen := map[string]bool{}
+	for _, tx := range pub.published {
+		msgs, err := enc.Encode(tx)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		for _, m := range msgs {
+			id := m.Headers["ce-id"]
+			if seen[id] {
+				t.Fatalf("duplicate event id %s", id)
+			}
+			seen[id] = true
+		}
+	}
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 events, got %d", len(seen))
+	}
+}
+
+// ackingPublisher acknowledges every event as soon as it is published, as
+// the file sink does when it flushes, and crashes on publish crashAt.
+type ackingPublisher struct {
+	recordingPublisher
+	crashAt int
+	commit  func(model.Offset)
+}
+
+func (p *ackingPublisher) OnCommit(fn func(model.Offset)) { p.commit = fn }
+
+func (p *ackingPublisher) Publish(evt model.Event) error {
+	if len(p.published) == p.crashAt {
+		return fmt.Errorf("crashed before publish %d", p.crashAt)
+	}
+	if err := p.recordingPublisher.Publish(evt); err != nil {
+		return err
+	}
+	p.commit(evt.Offset())
+	return nil
+}
+
+// TestRun_FragmentCrash stops publishing between two fragments; the
+// checkpoint must still point before the transaction, with its GTID set.
+func TestRun_FragmentCrash(t *testing.T) {
+	previous := model.MySQLOffset{File: "binlog.000001", Pos: 100, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-6"}
+	end := model.MySQLOffset{File: "binlog.000001", Pos: 900, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-7"}
+	rowsAt := model.MySQLOffset{File: "binlog.000001", Pos: 400}
+	now := time.Now()
+
+	row := func(id int) model.RowData {
+		return model.RowData{PK: map[string]any{"id": id}, After: map[string]any{"id": id}}
+	}
+	events := []model.Event{
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, previous, now, "tx:7", model.TxBegin),
+		model.NewBinlogRowEvent(model.SourceMySQLBinlog, rowsAt, now, "tx:7", []model.RowChange{{
+			Schema: "shop", Table: "orders", Op: model.OpInsert, Rows: []model.RowData{row(1), row(2), row(3)},
+		}}),
+		model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, end, now, "tx:7", model.TxCommit),
+	}
+
+	offsetPath := t.TempDir() + "/offset.json"
+	pub := &ackingPublisher{crashAt: 1}
+	s := &TabellariusSource{pub: pub, offsetPath: offsetPath, opts: Options{Fragment: FragmentOptions{MaxRows: 2}}}
+	pub.OnCommit(s.commit)
+
+	in := make(chan model.Event, len(events))
+	for _, evt := range events {
+		in <- evt
+	}
+	close(in)
+	s.run(context.Background(), in, true)
+
+	if len(pub.published) != 1 {
+		t.Fatalf("expected one fragment before the crash, got %d", len(pub.published))
+	}
+	got, ok := util.LoadJSON[model.MySQLOffset](offsetPath)
+	if !ok || got != previous {
+		t.Fatalf("expected checkpoint %v, got %v", previous, got)
+	}
+}
+
+func TestRun_FragmentsWithoutBegin(t *testing.T) {
+	end := model.MySQLOffset{File: "binlog.000001", Pos: 900}
+	now := time.Now()
+
+	pub := &recordingPublisher{}
+	s := &TabellariusSource{pub: pub, opts: Options{Fragment: FragmentOptions{MaxRows: 1}}}
+
+	in := make(chan model.Event, 2)
+	in <- model.NewBinlogRowEvent(model.SourceMySQLBinlog, end, now, "tx:1", []model.RowChange{{
+		Schema: "shop", Table: "orders", Op: model.OpInsert, Rows: []model.RowData{{PK: map[string]any{"id": 1}}, {PK: map[string]any{"id": 2}}},
+	}})
+	in <- model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, end, now, "tx:1", model.TxCommit)
+	close(in)
+	s.run(context.Background(), in, false)
+
+	if len(pub.published) != 1 {
+		t.Fatalf("expected the transaction published whole, got %d events", len(pub.published))
+	}
+	if _, ok := pub.published[0].Fragment(); ok {
+		t.Fatal("expected no fragment without a BEGIN boundary")
+	}
+}
+
+type closingPublisher struct {
+	recordingPublisher
+	closed bool
+}
+
+func (p *closingPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestRun_SwapsSinkBetweenTransactions(t *testing.T) {
+	off := model.MySQLOffset{File: "binlog.000001", Pos: 100}
+	now := time.Now()
+	rows := func(txID string) model.Event {
+		return model.NewBinlogRowEvent(model.SourceMySQLBinlog, off, now, txID, []model.RowChange{{
+			Schema: "shop", Table: "users", Op: model.OpInsert, Rows: []model.RowData{{After: map[string]any{"id": 1}}},
+		}})
+	}
+	commit := func(txID string) model.Event {
+		return model.NewTransactionBoundaryEvent(model.SourceMySQLBinlog, off, now, txID, model.TxCommit)
+	}
+
+	oldPub, newPub := &closingPublisher{}, &closingPublisher{}
+	s := &TabellariusSource{pub: oldPub, swap: make(chan Publisher), done: make(chan struct{})}
+	in := make(chan model.Event)
+	go func() {
+		defer close(s.done)
+		s.run(context.Background(), in, false)
+	}()
+
+	in <- rows("tx:1")
+	in <- commit("tx:1")
+	in <- rows("tx:2")
+	s.swap <- newPub
+	in <- commit("tx:2")
+	close(in)
+	<-s.done
+
+	if len(oldPub.published) != 1 || oldPub.published[0].TxID() != "tx:1" || !oldPub.closed {
+		t.Fatalf("old sink: published %d, closed %v", len(oldPub.published), oldPub.closed)
+	}
+	if len(newPub.published) != 1 || newPub.published[0].TxID() != "tx:2" {
+		t.Fatalf("open transaction not published to the new sink: %d", len(newPub.published))
+	}
+}
+
+func TestReload_Refused(t *testing.T) {
+	s := &TabellariusSource{pub: &recordingPublisher{}, swap: make(chan Publisher), done: make(chan struct{})}
+
+	cfg := &config.Config{Sink: config.Sink{Type: config.SinkHTTP}}
+	if err := s.Reload(cfg, config.Changes{Sink: true}); err == nil {
+		t.Fatal("expected a sink that cannot be built to be refused")
+	}
+	if err := s.Reload(cfg, config.Changes{AddedTables: []config.Table{{Name: "users"}}}); err == nil {
+		t.Fatal("expected table changes to be refused without a reloadable inspector")
+	}
+}
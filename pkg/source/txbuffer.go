@@ -0,0 +1,339 @@
+package source
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/cursus-io/tabellarius/pkg/metrics"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+const defaultBufferBytes = 256 << 20
+
+// Options tunes how a source buffers and publishes transactions.
+type Options struct {
+	Buffer   BufferOptions
+	Fragment FragmentOptions
+
+	// Metrics receives the source's metrics; nil means metrics.Default.
+	Metrics *metrics.Registry
+}
+
+// BufferOptions bounds the memory used by in-flight transactions.
+type BufferOptions struct {
+	// MaxMemoryBytes is the budget for all buffered transactions together
+	// (default 256MB). Transactions that would exceed it spill to SpillDir.
+	MaxMemoryBytes int64
+	// SpillDir holds spilled segments (default <tmp>/tabellarius-spill).
+	SpillDir string
+}
+
+// txBuffer holds the row changes of open transactions until they commit.
+// When the memory budget is exceeded, the transaction being appended to moves
+// to a segment file on disk and keeps growing there; Each reads the segment
+// back before the changes still in memory, so order is preserved.
+type txBuffer struct {
+	budget int64
+	dir    string
+
+	txs      map[string]*bufferedTx
+	memBytes int64
+
+	memGauge   *metrics.Gauge
+	diskGauge  *metrics.Gauge
+	txGauge    *metrics.Gauge
+	spillCount *metrics.Counter
+}
+
+type bufferedTx struct {
+	mem      []model.RowChange
+	memBytes int64
+
+	// segment holds the changes that were spilled, in order, ahead of mem.
+	segment   *os.File
+	w         *bufio.Writer
+	cw        *countingWriter
+	enc       *gob.Encoder
+	spilled   int
+	diskBytes int64
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newTxBuffer(opts BufferOptions, reg *metrics.Registry) *txBuffer {
+	if opts.MaxMemoryBytes <= 0 {
+		opts.MaxMemoryBytes = defaultBufferBytes
+	}
+	if opts.SpillDir == "" {
+		opts.SpillDir = filepath.Join(os.TempDir(), "tabellarius-spill")
+	}
+
+	// segments left by a previous run belong to transactions that will be
+	// replayed from the committed offset
+	if stale, err := filepath.Glob(filepath.Join(opts.SpillDir, "tx-*.seg")); err == nil {
+		for _, f := range stale {
+			os.Remove(f)
+		}
+	}
+
+	if reg == nil {
+		reg = metrics.Default
+	}
+
+	return &txBuffer{
+		budget:     opts.MaxMemoryBytes,
+		dir:        opts.SpillDir,
+		txs:        make(map[string]*bufferedTx),
+		memGauge:   reg.Gauge("txbuffer_memory_bytes"),
+		diskGauge:  reg.Gauge("txbuffer_disk_bytes"),
+		txGauge:    reg.Gauge("txbuffer_transactions"),
+		spillCount: reg.Counter("txbuffer_spills_total"),
+	}
+}
+
+// Append adds changes to txID. If spilling fails the changes stay in memory.
+func (b *txBuffer) Append(txID string, changes []model.RowChange) error {
+	tx, ok := b.txs[txID]
+	if !ok {
+		tx = &bufferedTx{}
+		b.txs[txID] = tx
+		b.txGauge.Add(1)
+	}
+
+	if tx.segment != nil {
+		return b.writeSegment(tx, changes)
+	}
+
+	var size int64
+	for _, c := range changes {
+		size += changeSize(c)
+	}
+	tx.mem = append(tx.mem, changes...)
+	tx.memBytes += size
+	b.addMem(size)
+
+	if b.memBytes > b.budget {
+		return b.spill(txID, tx)
+	}
+	return nil
+}
+
+// Len returns the number of changes buffered for txID.
+func (b *txBuffer) Len(txID string) int {
+	tx, ok := b.txs[txID]
+	if !ok {
+		return 0
+	}
+	return tx.spilled + len(tx.mem)
+}
+
+// Each calls fn with every change of txID in append order.
+func (b *txBuffer) Each(txID string, fn func(model.RowChange) error) error {
+	tx, ok := b.txs[txID]
+	if !ok {
+		return nil
+	}
+
+	if tx.segment != nil {
+		if err := tx.w.Flush(); err != nil {
+			return err
+		}
+		f, err := os.Open(tx.segment.Name())
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		for i := 0; i < tx.spilled; i++ {
+			var c model.RowChange
+			if err := dec.Decode(&c); err != nil {
+				return fmt.Errorf("read spilled change %d of %s: %w", i, txID, err)
+			}
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range tx.mem {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Take returns all changes of txID and removes it.
+func (b *txBuffer) Take(txID string) ([]model.RowChange, error) {
+	var out []model.RowChange
+	err := b.Each(txID, func(c model.RowChange) error {
+		out = append(out, c)
+		return nil
+	})
+	b.Discard(txID)
+	return out, err
+}
+
+// Truncate drops the changes of txID from index at onwards, except those
+// keep accepts.
+func (b *txBuffer) Truncate(txID string, at int, keep func(model.RowChange) bool) error {
+	changes, err := b.Take(txID)
+	if err != nil {
+		return err
+	}
+
+	kept := changes[:at:at]
+	for _, c := range changes[at:] {
+		if keep(c) {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return b.Append(txID, kept)
+}
+
+// Discard removes txID and its segment.
+func (b *txBuffer) Discard(txID string) {
+	tx, ok := b.txs[txID]
+	if !ok {
+		return
+	}
+	delete(b.txs, txID)
+	b.txGauge.Add(-1)
+	b.addMem(-tx.memBytes)
+
+	if tx.segment != nil {
+		tx.segment.Close()
+		os.Remove(tx.segment.Name())
+		b.diskGauge.Add(-tx.diskBytes)
+	}
+}
+
+// Count returns the number of open transactions.
+func (b *txBuffer) Count() int {
+	return len(b.txs)
+}
+
+// Close discards every transaction.
+func (b *txBuffer) Close() {
+	for txID := range b.txs {
+		b.Discard(txID)
+	}
+}
+
+func (b *txBuffer) spill(txID string, tx *bufferedTx) error {
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(b.dir, "tx-*.seg")
+	if err != nil {
+		return err
+	}
+
+	tx.segment = f
+	tx.w = bufio.NewWriter(f)
+	tx.cw = &countingWriter{w: tx.w}
+	tx.enc = gob.NewEncoder(tx.cw)
+	b.spillCount.Inc()
+
+	mem := tx.mem
+	tx.mem = nil
+	b.addMem(-tx.memBytes)
+	tx.memBytes = 0
+
+	if err := b.writeSegment(tx, mem); err != nil {
+		// keep what could not be written in memory, after what was
+		tx.mem = mem[tx.spilled:]
+		for _, c := range tx.mem {
+			tx.memBytes += changeSize(c)
+		}
+		b.addMem(tx.memBytes)
+		return fmt.Errorf("spill %s: %w", txID, err)
+	}
+	return nil
+}
+
+func (b *txBuffer) writeSegment(tx *bufferedTx, changes []model.RowChange) error {
+	for _, c := range changes {
+		registerValues(c)
+
+		before := tx.cw.n
+		if err := tx.enc.Encode(c); err != nil {
+			return err
+		}
+		n := tx.cw.n - before
+		tx.spilled++
+		tx.diskBytes += n
+		b.diskGauge.Add(n)
+	}
+	return nil
+}
+
+func (b *txBuffer) addMem(n int64) {
+	b.memBytes += n
+	b.memGauge.Add(n)
+}
+
+// changeSize estimates the memory held by a change.
+func changeSize(c model.RowChange) int64 {
+	size := int64(len(c.Schema) + len(c.Table) + 64)
+	for _, r := range c.Rows {
+		size += mapSize(r.PK) + mapSize(r.Before) + mapSize(r.After)
+	}
+	return size
+}
+
+func mapSize(m map[string]any) int64 {
+	var size int64
+	for k, v := range m {
+		size += int64(len(k)) + 16
+		switch x := v.(type) {
+		case string:
+			size += int64(len(x))
+		case []byte:
+			size += int64(len(x))
+		default:
+			size += 8
+		}
+	}
+	return size
+}
+
+var registered sync.Map
+
+// registerValues registers the concrete types of row values with gob, which
+// needs them to encode map[string]any.
+func registerValues(c model.RowChange) {
+	for _, r := range c.Rows {
+		for _, m := range []map[string]any{r.PK, r.Before, r.After} {
+			for _, v := range m {
+				if v == nil {
+					continue
+				}
+				t := reflect.TypeOf(v)
+				if _, ok := registered.LoadOrStore(t, true); !ok {
+					gob.Register(v)
+				}
+			}
+		}
+	}
+}
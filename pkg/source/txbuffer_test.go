@@ -0,0 +1,102 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func change(table string, id int64) model.RowChange {
+	return model.RowChange{
+		Schema: "shop",
+		Table:  table,
+		Op:     model.OpInsert,
+		Rows: []model.RowData{{
+			PK: map[string]any{"id": id},
+			After: map[string]any{
+				"id":      id,
+				"name":    fmt.Sprintf("row-%d", id),
+				"payload": []byte{0x01, 0x02},
+				"created": time.Unix(1700000000, 0).UTC(),
+				"deleted": nil,
+			},
+		}},
+	}
+}
+
+func TestTxBuffer_SpillPreservesOrder(t *testing.T) {
+	dir := t.TempDir()
+	buf := newTxBuffer(BufferOptions{MaxMemoryBytes: 512, SpillDir: dir}, nil)
+	defer buf.Close()
+
+	var want []model.RowChange
+	for i := int64(1); i <= 20; i++ {
+		c := change("orders", i)
+		want = append(want, c)
+		if err := buf.Append("tx-1", []model.RowChange{c}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := buf.Append("tx-2", []model.RowChange{change("audit", 99)}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "tx-*.seg"))
+	if len(segments) == 0 {
+		t.Fatalf("expected a spilled segment")
+	}
+	if buf.Len("tx-1") != 20 {
+		t.Fatalf("expected 20 buffered changes, got %d", buf.Len("tx-1"))
+	}
+
+	got, err := buf.Take("tx-1")
+	if err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes differ after spill:\n got %+v\nwant %+v", got[0], want[0])
+	}
+
+	if _, err := os.Stat(segments[0]); !os.IsNotExist(err) {
+		t.Fatalf("expected segment to be removed after take")
+	}
+	if buf.Count() != 1 {
+		t.Fatalf("expected tx-2 to remain, got %d transactions", buf.Count())
+	}
+}
+
+func TestTxBuffer_TruncateSpilled(t *testing.T) {
+	buf := newTxBuffer(BufferOptions{MaxMemoryBytes: 256, SpillDir: t.TempDir()}, nil)
+	defer buf.Close()
+
+	for i := int64(1); i <= 6; i++ {
+		c := change("orders", i)
+		c.NonTransactional = i == 5
+		if err := buf.Append("tx-1", []model.RowChange{c}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+
+	keep := func(c model.RowChange) bool { return c.NonTransactional }
+	if err := buf.Truncate("tx-1", 3, keep); err != nil {
+		t.Fatalf("truncate failed: %v", err)
+	}
+
+	got, err := buf.Take("tx-1")
+	if err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+
+	var ids []any
+	for _, c := range got {
+		ids = append(ids, c.Rows[0].PK["id"])
+	}
+	if fmt.Sprint(ids) != "[1 2 3 5]" {
+		t.Fatalf("unexpected changes after truncate: %v", ids)
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/format"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+// KeyHeader carries the base64 encoded message key, if any.
+const KeyHeader = "X-Message-Key"
+
+// Publisher POSTs each encoded message to an HTTP endpoint. Message headers
+// (e.g. CloudEvents ce-* attributes) are sent as request headers.
+type Publisher struct {
+	url     string
+	headers map[string]string
+	enc     format.Encoder
+	client  *http.Client
+}
+
+func NewPublisher(url string, headers map[string]string, timeout time.Duration, enc format.Encoder) *Publisher {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Publisher{
+		url:     url,
+		headers: headers,
+		enc:     enc,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *Publisher) Publish(evt model.Event) error {
+	msgs, err := p.enc.Encode(evt)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for _, msg := range msgs {
+		// an HTTP request without a body has no meaning as a tombstone
+		if msg.Value == nil {
+			continue
+		}
+		if err := p.post(msg); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[http] published source=%s offset=%s messages=%d", evt.Source(), evt.Offset().String(), len(msgs))
+	return nil
+}
+
+func (p *Publisher) post(msg format.Message) error {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(msg.Value))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range msg.Headers {
+		req.Header.Set(k, v)
+	}
+	if msg.Key != nil {
+		req.Header.Set(KeyHeader, base64.StdEncoding.EncodeToString(msg.Key))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to post message: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
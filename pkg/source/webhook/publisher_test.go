@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cursus-io/tabellarius/pkg/format"
+	"github.com/cursus-io/tabellarius/pkg/model"
+)
+
+func TestPublisher_CloudEventsBinary(t *testing.T) {
+	var gotHeader http.Header
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer srv.Close()
+
+	enc, _ := format.NewCloudEventsEncoder("dbserver1", format.CloudEventsBinary)
+	p := NewPublisher(srv.URL, map[string]string{"Authorization": "Bearer t"}, time.Second, enc)
+
+	evt := model.NewTransactionEvent(model.SourceMySQLBinlog, model.MySQLOffset{File: "binlog.000001", Pos: 4}, time.Now(), "tx-1", []model.RowChange{{
+		Schema: "mydb",
+		Table:  "users",
+		Op:     model.OpInsert,
+		Rows:   []model.RowData{{After: map[string]any{"id": 1}}},
+	}}, model.TxMeta{})
+
+	if err := p.Publish(evt); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	if gotHeader.Get("Ce-Id") != "tx-1#0" || gotHeader.Get("Ce-Type") != "io.tabellarius.row.insert" {
+		t.Fatalf("missing cloudevents headers: %v", gotHeader)
+	}
+	if gotHeader.Get("Authorization") != "Bearer t" {
+		t.Fatal("configured headers not sent")
+	}
+	if gotBody != `{"after":{"id":1}}` {
+		t.Fatalf("unexpected body: %s", gotBody)
+	}
+}
+
+func TestPublisher_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p := NewPublisher(srv.URL, nil, time.Second, format.JSONEncoder{})
+	ddl := model.NewBinlogDDLEvent(model.SourceMySQLBinlog, model.MySQLOffset{}, time.Now(), "", "mydb", "DROP TABLE t")
+
+	if err := p.Publish(ddl); err == nil {
+		t.Fatal("expected error on 503")
+	}
+}
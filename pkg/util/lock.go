@@ -0,0 +1,61 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned by LockFile when another process holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
+// FileLock is an exclusive advisory lock on a file, released by Unlock or
+// when the process exits.
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes the lock on path without waiting, creating the file if
+// needed, and records the pid of the holder in it. When the lock is taken
+// the error wraps ErrLocked and names the holder's pid.
+func LockFile(path string) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := tryLock(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !ok {
+		f.Close()
+		if pid := lockHolder(path); pid != 0 {
+			return nil, fmt.Errorf("%s: %w (pid %d)", path, ErrLocked, pid)
+		}
+		return nil, fmt.Errorf("%s: %w", path, ErrLocked)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &FileLock{f: f}, nil
+}
+
+func lockHolder(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
+
+func (l *FileLock) Unlock() error {
+	// clear the pid so it is not reported for a later holder; closing the
+	// file releases the lock
+	_ = l.f.Truncate(0)
+	return l.f.Close()
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// tryLock fails: this platform has no file locks.
+func tryLock(f *os.File) (bool, error) {
+	return false, fmt.Errorf("lock %s: %w on %s", f.Name(), errors.ErrUnsupported, runtime.GOOS)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package util
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// tryLock locks a byte of f without waiting. It reports false when another
+// process holds it. Windows locks are mandatory, so the byte lies far past
+// the pid, which stays readable to lockHolder.
+func tryLock(f *os.File) (bool, error) {
+	ol := &windows.Overlapped{Offset: 0xffffffff, OffsetHigh: 0x7fffffff}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}